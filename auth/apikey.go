@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// APIKeyHeader carries the API key of a partner integration.
+const APIKeyHeader = "X-API-Key"
+
+// KeyVerifier looks up the API key presented by a request, failing with a
+// models.UnauthenticatedError if it is unknown, revoked or expired.
+type KeyVerifier interface {
+	VerifyKey(ctx context.Context, key string) (models.APIKey, error)
+}
+
+// APIKeyAuthenticator authenticates requests carrying an X-API-Key. Its
+// principals have the key's scopes instead of a role.
+type APIKeyAuthenticator struct {
+	keys KeyVerifier
+}
+
+func NewAPIKeyAuthenticator(keys KeyVerifier) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{
+		keys: keys,
+	}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	raw := r.Header.Get(APIKeyHeader)
+	if raw == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	key, err := a.keys.VerifyKey(r.Context(), raw)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: "api-key:" + key.ID.String(), APIKeyID: key.ID.String(), Scopes: key.Scopes}, nil
+}
+
+// scopeResources maps the first segment of a route to the resource its
+// scopes are named after.
+var scopeResources = map[string]string{
+	"cars":   "cars",
+	"vin":    "cars",
+	"engine": "engines",
+}
+
+// RequiredScopes returns the scopes an API key needs for the request's
+// route: the resource's read scope for reads, its delete scope for deletes
+// and its write scope for everything else. Deleting an engine along with
+// its cars also needs the scope to delete or change them, and creating a
+// car with an inline engine the scope to create engines. Routes outside
+// cars and engines, such as the admin endpoints, and purges of the trash
+// cannot be used with an API key at all and report false, as they are
+// reserved for admins.
+func RequiredScopes(r *http.Request) ([]string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil, false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return nil, false
+	}
+	segments := strings.Split(strings.TrimPrefix(template, "/"), "/")
+	resource, ok := scopeResources[segments[0]]
+	if !ok {
+		return nil, false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return []string{resource + ":read"}, true
+	case http.MethodDelete:
+		if len(segments) > 1 && segments[1] == "trash" {
+			return nil, false
+		}
+		scopes := []string{resource + ":delete"}
+		if resource == "engines" {
+			switch r.URL.Query().Get("strategy") {
+			case models.DeleteCascade:
+				scopes = append(scopes, models.ScopeCarsDelete)
+			case models.DeleteDetach:
+				scopes = append(scopes, models.ScopeCarsWrite)
+			}
+		}
+		return scopes, true
+	default:
+		scopes := []string{resource + ":write"}
+		if r.Method == http.MethodPost && template == "/cars" && createsEngine(r) {
+			scopes = append(scopes, models.ScopeEnginesWrite)
+		}
+		return scopes, true
+	}
+}
+
+// createsEngine reports whether a new car's body leaves out engine_id, so
+// the car's engine would be created along with it. The body is put back for
+// the handler. A body that cannot be read or decoded is taken to create
+// one, so the scope check never depends on the handler rejecting it.
+func createsEngine(r *http.Request) bool {
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil {
+		return true
+	}
+	var carReq struct {
+		Engine struct {
+			EngineID uuid.UUID `json:"engine_id"`
+		} `json:"engine"`
+	}
+	if err := json.Unmarshal(body, &carReq); err != nil {
+		return true
+	}
+	return carReq.Engine.EngineID == uuid.Nil
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// stubKeys verifies keys by looking their scopes up by the key itself.
+type stubKeys map[string][]string
+
+func (k stubKeys) VerifyKey(ctx context.Context, key string) (models.APIKey, error) {
+	scopes, ok := k[key]
+	if !ok {
+		return models.APIKey{}, models.NewUnauthenticatedError("invalid API key")
+	}
+	return models.APIKey{ID: uuid.New(), Scopes: scopes}, nil
+}
+
+func newTestRouter() *mux.Router {
+	keys := stubKeys{
+		"write":          {models.ScopeCarsRead, models.ScopeCarsWrite, models.ScopeEnginesRead, models.ScopeEnginesWrite},
+		"delete":         {models.ScopeCarsRead, models.ScopeCarsWrite, models.ScopeCarsDelete, models.ScopeEnginesRead, models.ScopeEnginesWrite, models.ScopeEnginesDelete},
+		"engines-delete": {models.ScopeEnginesRead, models.ScopeEnginesWrite, models.ScopeEnginesDelete},
+		"cars-write":     {models.ScopeCarsRead, models.ScopeCarsWrite},
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	router := mux.NewRouter()
+	router.Use(Middleware(NewAPIKeyAuthenticator(keys)))
+	router.HandleFunc("/cars/trash", ok).Methods("DELETE")
+	router.HandleFunc("/cars/{id}", ok).Methods("GET", "PUT", "DELETE")
+	router.HandleFunc("/cars", ok).Methods("POST")
+	router.HandleFunc("/engine/trash", ok).Methods("DELETE")
+	router.HandleFunc("/engine/{id}", ok).Methods("DELETE")
+	router.HandleFunc("/admin/api-keys", ok).Methods("GET")
+	return router
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	router := newTestRouter()
+	id := uuid.NewString()
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"write key creates a car", "write", "POST", "/cars", `{"engine":{"engine_id":"` + id + `"}}`, http.StatusNoContent},
+		{"write key creates a car with an inline engine", "write", "POST", "/cars", `{"engine":{"displacement":2000}}`, http.StatusNoContent},
+		{"cars write key creates a car", "cars-write", "POST", "/cars", `{"name":"Civic","engine":{"engine_id":"` + id + `"}}`, http.StatusNoContent},
+		{"cars write key may not create an inline engine", "cars-write", "POST", "/cars", `{"name":"Civic","engine":{"displacement":2000}}`, http.StatusForbidden},
+		{"cars write key may not leave the engine out", "cars-write", "POST", "/cars", `{"name":"Civic"}`, http.StatusForbidden},
+		{"cars write key may not send a null engine_id", "cars-write", "POST", "/cars", `{"engine":{"engine_id":null}}`, http.StatusForbidden},
+		{"cars write key may not send an unreadable body", "cars-write", "POST", "/cars", `{"engine":`, http.StatusForbidden},
+		{"cars write key updates a car without engine_id", "cars-write", "PUT", "/cars/" + id, `{"engine":{"displacement":2000}}`, http.StatusNoContent},
+		{"write key updates a car", "write", "PUT", "/cars/" + id, "", http.StatusNoContent},
+		{"write key may not delete a car", "write", "DELETE", "/cars/" + id, "", http.StatusForbidden},
+		{"write key may not purge cars", "write", "DELETE", "/cars/trash", "", http.StatusForbidden},
+		{"write key may not purge engines", "write", "DELETE", "/engine/trash", "", http.StatusForbidden},
+		{"write key may not cascade an engine delete", "write", "DELETE", "/engine/" + id + "?strategy=cascade", "", http.StatusForbidden},
+		{"write key may not detach an engine's cars", "write", "DELETE", "/engine/" + id + "?strategy=detach", "", http.StatusForbidden},
+		{"delete key deletes a car", "delete", "DELETE", "/cars/" + id, "", http.StatusNoContent},
+		{"delete key cascades an engine delete", "delete", "DELETE", "/engine/" + id + "?strategy=cascade", "", http.StatusNoContent},
+		{"delete key may not purge cars", "delete", "DELETE", "/cars/trash", "", http.StatusForbidden},
+		{"delete key may not purge engines", "delete", "DELETE", "/engine/trash", "", http.StatusForbidden},
+		{"engine delete key deletes an unused engine", "engines-delete", "DELETE", "/engine/" + id, "", http.StatusNoContent},
+		{"engine delete key may not cascade to cars", "engines-delete", "DELETE", "/engine/" + id + "?strategy=cascade", "", http.StatusForbidden},
+		{"engine delete key may not detach cars", "engines-delete", "DELETE", "/engine/" + id + "?strategy=detach", "", http.StatusForbidden},
+		{"keys may not use admin endpoints", "delete", "GET", "/admin/api-keys", "", http.StatusForbidden},
+		{"unknown key", "nope", "GET", "/cars/" + id, "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			req.Header.Set(APIKeyHeader, test.key)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != test.want {
+				t.Errorf("%s %s got %d, want %d: %s", test.method, test.target, rec.Code, test.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRequiredScopesKeepsTheBody(t *testing.T) {
+	const body = `{"name":"Civic","engine":{"displacement":2000}}`
+	var got string
+	router := mux.NewRouter()
+	router.Use(Middleware(NewAPIKeyAuthenticator(stubKeys{"write": {models.ScopeCarsWrite, models.ScopeEnginesWrite}})))
+	router.HandleFunc("/cars", func(w http.ResponseWriter, r *http.Request) {
+		read, _ := io.ReadAll(r.Body)
+		got = string(read)
+	}).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/cars", strings.NewReader(body))
+	req.Header.Set(APIKeyHeader, "write")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if got != body {
+		t.Errorf("the handler read %q, want %q", got, body)
+	}
+}
+
+func TestIsAPIKey(t *testing.T) {
+	if (Principal{Subject: "alice", Role: RoleEditor, Scopes: []string{}}).IsAPIKey() {
+		t.Error("a user with an empty scope list was taken for an API key")
+	}
+	if !(Principal{Subject: "api-key:1", APIKeyID: "1"}).IsAPIKey() {
+		t.Error("an API key without scopes was not recognised as one")
+	}
+}
@@ -0,0 +1,162 @@
+// Package auth authenticates API requests and checks that the caller may
+// use the route it asked for.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/audit"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// Role is what a principal is allowed to do. Each role includes the ones
+// below it: viewers read, editors also create and change, and admins also
+// delete and maintain the admin endpoints.
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether the role grants everything the other one does.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// Principal is the authenticated caller of a request. A user has a role; an
+// API key has scopes instead.
+type Principal struct {
+	Subject string
+	Role    Role
+	// APIKeyID is set when the principal was authenticated by an API key.
+	APIKeyID string
+	Scopes   []string
+}
+
+// IsAPIKey reports whether the principal was authenticated by an API key.
+func (p Principal) IsAPIKey() bool {
+	return p.APIKeyID != ""
+}
+
+// Key identifies the principal among users and API keys alike, for state
+// kept per caller.
+func (p Principal) Key() string {
+	if p.IsAPIKey() {
+		return p.Subject
+	}
+	return "user:" + p.Subject
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// ErrNoCredentials is returned by an Authenticator when the request carries
+// none of the credentials it understands, so the next one can be tried.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator turns the credentials of a request into a principal. It
+// fails with a models.UnauthenticatedError when they are present but not
+// valid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// RequiredRole returns the role needed for the request's route: admin for
+// everything under /admin and for deletes, editor for the other mutations
+// and viewer for reads. It must run after the router matched the route.
+func RequiredRole(r *http.Request) Role {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil && strings.HasPrefix(template, "/admin/") {
+			return RoleAdmin
+		}
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer
+	case http.MethodDelete:
+		return RoleAdmin
+	default:
+		return RoleEditor
+	}
+}
+
+// Middleware authenticates each request with the first authenticator that
+// finds credentials on it and rejects it with 401 if none does, or with
+// 403 if the principal's role or scopes do not allow the route. The
+// principal is put in the request context and recorded as the actor of the
+// audit history.
+func Middleware(authenticators ...Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticate(r, authenticators)
+			if err != nil {
+				handler.WriteError(w, r, err)
+				return
+			}
+			if err := authorize(principal, r); err != nil {
+				handler.WriteError(w, r, err)
+				return
+			}
+			ctx := WithPrincipal(r.Context(), principal)
+			ctx = audit.WithActor(ctx, principal.Subject)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func authorize(principal Principal, r *http.Request) error {
+	if principal.IsAPIKey() {
+		scopes, ok := RequiredScopes(r)
+		if !ok {
+			return models.NewForbiddenError("API keys may not " + r.Method + " " + r.URL.Path)
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(principal.Scopes, scope) {
+				return models.NewForbiddenError("the API key may not " + r.Method + " " + r.URL.Path + ", the " + scope + " scope is required")
+			}
+		}
+		return nil
+	}
+	if required := RequiredRole(r); !principal.Role.Includes(required) {
+		return models.NewForbiddenError("the " + string(principal.Role) + " role may not " + r.Method + " " + r.URL.Path + ", " + string(required) + " is required")
+	}
+	return nil
+}
+
+func authenticate(r *http.Request, authenticators []Authenticator) (Principal, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return principal, err
+	}
+	return Principal{}, models.NewUnauthenticatedError("authentication is required")
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// minHS256SecretLength is the shortest HS256 secret accepted, the size of
+// the hash as RFC 7518 requires.
+const minHS256SecretLength = 32
+
+// clockSkew is how far the clocks of the token issuer and this server may
+// drift apart before exp and nbf are enforced.
+const clockSkew = 30 * time.Second
+
+// JWTConfig holds the keys tokens may be signed with, and the issuer and
+// audience they must name when set. At least one key is required; a token
+// signed with an algorithm that has no key is rejected.
+type JWTConfig struct {
+	HS256Secret    []byte
+	RS256PublicKey *rsa.PublicKey
+	Issuer         string
+	Audience       string
+}
+
+// JWTAuthenticator authenticates requests carrying a bearer JWT. The token
+// names the principal in sub and its role in a role claim.
+type JWTAuthenticator struct {
+	config JWTConfig
+}
+
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	if config.HS256Secret == nil && config.RS256PublicKey == nil {
+		return nil, errors.New("no JWT key configured")
+	}
+	if config.HS256Secret != nil && len(config.HS256Secret) < minHS256SecretLength {
+		return nil, errors.New("the HS256 secret must be at least 32 bytes long")
+	}
+	return &JWTAuthenticator{
+		config: config,
+	}, nil
+}
+
+// ParseRSAPublicKey reads a PEM encoded RSA public key, either a PKIX
+// "PUBLIC KEY" or a PKCS #1 "RSA PUBLIC KEY".
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("the public key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Role      Role     `json:"role"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+}
+
+// audience is the aud claim, which may be a single string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return Principal{}, models.NewUnauthenticatedError("the Authorization header must be a Bearer token")
+	}
+	return a.Verify(strings.TrimSpace(token), time.Now())
+}
+
+// Verify checks the token's signature and claims at the given time and
+// returns the principal it names.
+func (a *JWTAuthenticator) Verify(token string, now time.Time) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, invalidToken("it is not a JWT")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, invalidToken("the header is malformed")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, invalidToken("the signature is malformed")
+	}
+	if err := a.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, invalidToken("the claims are malformed")
+	}
+	switch {
+	case claims.ExpiresAt == nil:
+		return Principal{}, invalidToken("it has no exp claim")
+	case now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)):
+		return Principal{}, invalidToken("it has expired")
+	case claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)):
+		return Principal{}, invalidToken("it is not valid yet")
+	case a.config.Issuer != "" && claims.Issuer != a.config.Issuer:
+		return Principal{}, invalidToken("it was not issued by " + a.config.Issuer)
+	case a.config.Audience != "" && !slices.Contains(claims.Audience, a.config.Audience):
+		return Principal{}, invalidToken("it is not meant for " + a.config.Audience)
+	case claims.Subject == "":
+		return Principal{}, invalidToken("it has no sub claim")
+	case !claims.Role.Valid():
+		return Principal{}, invalidToken("its role claim must be one of: viewer, editor, admin")
+	}
+	return Principal{Subject: claims.Subject, Role: claims.Role}, nil
+}
+
+// verifySignature checks the signature with the key of the algorithm named
+// in the header. Only algorithms with a configured key are accepted, so a
+// token cannot pick a weaker one, or none.
+func (a *JWTAuthenticator) verifySignature(alg, signed string, signature []byte) error {
+	switch {
+	case alg == "HS256" && a.config.HS256Secret != nil:
+		mac := hmac.New(sha256.New, a.config.HS256Secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return invalidToken("the signature does not match")
+		}
+		return nil
+	case alg == "RS256" && a.config.RS256PublicKey != nil:
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(a.config.RS256PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return invalidToken("the signature does not match")
+		}
+		return nil
+	default:
+		return invalidToken("it is signed with " + alg + ", which is not accepted")
+	}
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func invalidToken(reason string) error {
+	return models.NewUnauthenticatedError("invalid token: " + reason)
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// unsignedToken returns the header and claims of a token, encoded and
+// joined, ready to be signed.
+func unsignedToken(t *testing.T, alg string, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func hs256Token(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	signed := unsignedToken(t, "HS256", claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256Token(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	signed := unsignedToken(t, "RS256", claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func claimsFor(role Role, now time.Time) map[string]any {
+	return map[string]any{"sub": "alice", "role": role, "exp": now.Add(time.Hour).Unix()}
+}
+
+func newJWTAuthenticator(t *testing.T, config JWTConfig) *JWTAuthenticator {
+	t.Helper()
+	authenticator, err := NewJWTAuthenticator(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator
+}
+
+func TestJWTVerify(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &key.PublicKey)})
+	hs := newJWTAuthenticator(t, JWTConfig{HS256Secret: testSecret})
+	rs := newJWTAuthenticator(t, JWTConfig{RS256PublicKey: &key.PublicKey})
+	both := newJWTAuthenticator(t, JWTConfig{HS256Secret: testSecret, RS256PublicKey: &key.PublicKey, Issuer: "drivethrough", Audience: "api"})
+
+	with := func(changes map[string]any) map[string]any {
+		claims := claimsFor(RoleEditor, now)
+		for name, value := range changes {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+	valid := hs256Token(t, testSecret, claimsFor(RoleEditor, now))
+	tampered := strings.Split(valid, ".")
+	tampered[1] = strings.Split(hs256Token(t, testSecret, claimsFor(RoleAdmin, now)), ".")[1]
+
+	tests := []struct {
+		name          string
+		authenticator *JWTAuthenticator
+		token         string
+		reason        string
+	}{
+		{"HS256", hs, valid, ""},
+		{"RS256", rs, rs256Token(t, key, claimsFor(RoleEditor, now)), ""},
+		{"RS256 with both keys", both, rs256Token(t, key, with(map[string]any{"iss": "drivethrough", "aud": []string{"web", "api"}})), ""},
+		{"exp within the clock skew", hs, hs256Token(t, testSecret, with(map[string]any{"exp": now.Add(-clockSkew).Unix()})), ""},
+		{"alg none", hs, unsignedToken(t, "none", claimsFor(RoleAdmin, now)) + ".", "signed with none"},
+		{"alg none with a signature", hs, unsignedToken(t, "none", claimsFor(RoleAdmin, now)) + "." + strings.Split(valid, ".")[2], "signed with none"},
+		{"HS256 signed with the RSA public key", rs, hs256Token(t, publicPEM, claimsFor(RoleAdmin, now)), "signed with HS256"},
+		{"HS256 signed with the RSA public key, both keys", both, hs256Token(t, publicPEM, with(map[string]any{"iss": "drivethrough", "aud": "api"})), "signature does not match"},
+		{"RS256 header on an HMAC signature", rs, unsignedToken(t, "RS256", claimsFor(RoleAdmin, now)) + "." + strings.Split(valid, ".")[2], "signature does not match"},
+		{"RS256 without an RSA key", hs, rs256Token(t, key, claimsFor(RoleEditor, now)), "signed with RS256"},
+		{"wrong secret", hs, hs256Token(t, []byte("fedcba9876543210fedcba9876543210"), claimsFor(RoleEditor, now)), "signature does not match"},
+		{"tampered claims", hs, strings.Join(tampered, "."), "signature does not match"},
+		{"expired", hs, hs256Token(t, testSecret, with(map[string]any{"exp": now.Add(-time.Minute).Unix()})), "expired"},
+		{"no exp", hs, hs256Token(t, testSecret, with(map[string]any{"exp": nil})), "no exp claim"},
+		{"not valid yet", hs, hs256Token(t, testSecret, with(map[string]any{"nbf": now.Add(time.Minute).Unix()})), "not valid yet"},
+		{"wrong issuer", both, hs256Token(t, testSecret, with(map[string]any{"iss": "elsewhere", "aud": "api"})), "not issued by"},
+		{"wrong audience", both, hs256Token(t, testSecret, with(map[string]any{"iss": "drivethrough", "aud": "web"})), "not meant for"},
+		{"no sub", hs, hs256Token(t, testSecret, with(map[string]any{"sub": nil})), "no sub claim"},
+		{"unknown role", hs, hs256Token(t, testSecret, with(map[string]any{"role": "owner"})), "role claim"},
+		{"not a JWT", hs, "token", "not a JWT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := test.authenticator.Verify(test.token, now)
+			if test.reason == "" {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if principal.Subject != "alice" || principal.Role != RoleEditor {
+					t.Errorf("got principal %+v, want alice the editor", principal)
+				}
+				return
+			}
+			if !errors.Is(err, models.ErrUnauthenticated) || !strings.Contains(err.Error(), test.reason) {
+				t.Fatalf("got %v, want an unauthenticated error saying %q", err, test.reason)
+			}
+		})
+	}
+}
+
+func mustMarshalPKIX(t *testing.T, key *rsa.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestNewJWTAuthenticator(t *testing.T) {
+	if _, err := NewJWTAuthenticator(JWTConfig{}); err == nil {
+		t.Error("accepted a config without keys")
+	}
+	if _, err := NewJWTAuthenticator(JWTConfig{HS256Secret: []byte("short")}); err == nil {
+		t.Error("accepted a short HS256 secret")
+	}
+}
+
+func TestJWTRoles(t *testing.T) {
+	now := time.Now()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	router := mux.NewRouter()
+	router.Use(Middleware(newJWTAuthenticator(t, JWTConfig{HS256Secret: testSecret})))
+	router.HandleFunc("/cars", ok).Methods("GET", "POST")
+	router.HandleFunc("/cars/{id}", ok).Methods("DELETE")
+	router.HandleFunc("/admin/api-keys", ok).Methods("GET")
+
+	tests := []struct {
+		name          string
+		authorization string
+		method        string
+		target        string
+		want          int
+	}{
+		{"viewer reads", "Bearer " + hs256Token(t, testSecret, claimsFor(RoleViewer, now)), "GET", "/cars", http.StatusNoContent},
+		{"viewer may not create", "Bearer " + hs256Token(t, testSecret, claimsFor(RoleViewer, now)), "POST", "/cars", http.StatusForbidden},
+		{"editor creates", "Bearer " + hs256Token(t, testSecret, claimsFor(RoleEditor, now)), "POST", "/cars", http.StatusNoContent},
+		{"editor may not delete", "Bearer " + hs256Token(t, testSecret, claimsFor(RoleEditor, now)), "DELETE", "/cars/1", http.StatusForbidden},
+		{"editor may not use admin endpoints", "Bearer " + hs256Token(t, testSecret, claimsFor(RoleEditor, now)), "GET", "/admin/api-keys", http.StatusForbidden},
+		{"admin deletes", "Bearer " + hs256Token(t, testSecret, claimsFor(RoleAdmin, now)), "DELETE", "/cars/1", http.StatusNoContent},
+		{"missing token", "", "GET", "/cars", http.StatusUnauthorized},
+		{"not a bearer token", "Basic YWxpY2U6c2VjcmV0", "GET", "/cars", http.StatusUnauthorized},
+		{"invalid token", "Bearer " + hs256Token(t, []byte("fedcba9876543210fedcba9876543210"), claimsFor(RoleAdmin, now)), "GET", "/cars", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != test.want {
+				t.Fatalf("%s %s got %d, want %d: %s", test.method, test.target, rec.Code, test.want, rec.Body)
+			}
+			if test.want == http.StatusNoContent {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("got Content-Type %q, want application/problem+json", got)
+			}
+			var problem handler.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Status != test.want || problem.Detail == "" || problem.Instance != test.target {
+				t.Errorf("got problem %+v", problem)
+			}
+			if test.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("no WWW-Authenticate header")
+			}
+		})
+	}
+}
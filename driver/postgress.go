@@ -4,7 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 )
@@ -20,21 +20,23 @@ func InitDB() {
 		os.Getenv("DB_NAME"),
 	)
 
-	fmt.Println("Starting up Database....")
+	slog.Info("starting up database")
 	time.Sleep(5 * time.Second)
 
 	var err error
 	db, err = sql.Open("postgres", connStr) // ✅ assign to the package-level variable
 	if err != nil {
-		log.Fatalf("error opening database: %v", err)
+		slog.Error("error opening database", "error", err)
+		os.Exit(1)
 	}
 
 	err = db.Ping()
 	if err != nil {
-		log.Fatalf("error connecting to database: %v", err)
+		slog.Error("error connecting to database", "error", err)
+		os.Exit(1)
 	}
 
-	fmt.Println("Successfully connected to the database!")
+	slog.Info("connected to the database")
 }
 
 func GetDB() *sql.DB {
@@ -44,7 +46,8 @@ func GetDB() *sql.DB {
 func CloseDB() {
 	if db != nil {
 		if err := db.Close(); err != nil {
-			log.Fatalf("Error closing database: %v", err)
+			slog.Error("error closing database", "error", err)
+			os.Exit(1)
 		}
 	}
 }
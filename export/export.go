@@ -0,0 +1,67 @@
+// Package export writes tabular data to spreadsheet formats one row at a
+// time, so large exports can be streamed straight into a response.
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// TableWriter writes a table row by row. Close must be called to finish the
+// document; until then the output may be incomplete.
+type TableWriter interface {
+	WriteRow(cells []any) error
+	Close() error
+}
+
+// Decimal is a number already in decimal notation, such as an exact money
+// amount. It is written as a number without going through a float.
+type Decimal string
+
+type CSVWriter struct {
+	w *csv.Writer
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) WriteRow(cells []any) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = formatCell(cell)
+	}
+	return c.w.Write(record)
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatCell renders a cell as text. Times use RFC 3339 so they sort and
+// parse the same everywhere.
+func formatCell(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case Decimal:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case interface{ String() string }:
+		return v.String()
+	default:
+		return ""
+	}
+}
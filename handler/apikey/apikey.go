@@ -0,0 +1,68 @@
+package apikey
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/service"
+)
+
+// APIKeyHandler serves the admin endpoints that issue and revoke the API
+// keys of partner integrations.
+type APIKeyHandler struct {
+	service service.APIKeyServiceInterface
+}
+
+func NewAPIKeyHandler(service service.APIKeyServiceInterface) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+	}
+}
+
+// IssueKey creates a key from {"name": ..., "scopes": [...], "expires_at":
+// ...}. The response is the only place the key itself ever appears.
+func (h *APIKeyHandler) IssueKey(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error reading request body", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var req models.APIKeyRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		slog.WarnContext(r.Context(), "error while un-marshalling request", "error", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "request body is not valid JSON")
+		return
+	}
+	key, err := h.service.IssueKey(r.Context(), &req)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.ListKeys(r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, keys)
+}
+
+// RevokeKey revokes a key for good. The key stays listed with its
+// revocation time.
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.service.RevokeKey(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, key)
+}
@@ -3,10 +3,12 @@ package car
 import (
 	"encoding/json"
 	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/mergepatch"
 	"github.com/pranayyb/DriveThrough/models"
 	"github.com/pranayyb/DriveThrough/service"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -24,16 +26,16 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	id := vars["id"]
-	res, err := h.service.GetCarById(id, ctx)
+	res, err := h.service.GetCarById(id, r.URL.Query().Get("currency"), ctx)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("error: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
+	handler.SetETag(w, res.Version)
 	body, err := json.Marshal(res)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("error: ", err)
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		return
 	}
 
@@ -42,25 +44,27 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(body)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
 	}
 }
 
-func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
+func (h *CarHandler) ListCars(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	brand := r.URL.Query().Get("brand")
-	isEngine := r.URL.Query().Get("isEngine") == "true"
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
 
-	res, err := h.service.GetCarByBrand(brand, ctx, isEngine)
+	res, err := h.service.ListCars(filter, ctx)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("error: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
 	body, err := json.Marshal(res)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		return
 	}
 
@@ -69,7 +73,7 @@ func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(body)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
 	}
 }
 
@@ -77,7 +81,7 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("error: ", err)
+		slog.ErrorContext(ctx, "error reading request body", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -85,21 +89,19 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 	var carReq models.CarRequest
 	err = json.Unmarshal(body, &carReq)
 	if err != nil {
-		log.Println("error while un-marshalling request: ", err)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.WriteDecodeError(w, r, err)
 		return
 	}
 
 	createdCar, err := h.service.CreateCar(&carReq, ctx)
 	if err != nil {
-		log.Println("error while creating car: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	responseBody, err := json.Marshal(createdCar)
 	if err != nil {
-		log.Println("error while marshalling: ", err)
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -108,7 +110,7 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
 	}
 }
 
@@ -117,9 +119,14 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
 	id := params["id"]
+	version, ok := handler.IfMatchVersion(r)
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("Err Reading Request body:", err)
+		slog.ErrorContext(ctx, "error reading request body", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -127,20 +134,64 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	var carReq models.CarRequest
 	err = json.Unmarshal(body, &carReq)
 	if err != nil {
-		log.Println("Error while Un-marshalling Request body", err)
+		handler.WriteDecodeError(w, r, err)
+		return
+	}
+
+	updatedCar, err := h.service.UpdateCar(id, &carReq, version, ctx)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.SetETag(w, updatedCar.Version)
+	responseBody, err := json.Marshal(updatedCar)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 
-	updatedCar, err := h.service.UpdateCar(id, &carReq, ctx)
+	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("Error while Updating the Car :", err)
+		slog.ErrorContext(ctx, "error writing response", "error", err)
+	}
+}
+
+func (h *CarHandler) PatchCar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	id := params["id"]
+	version, ok := handler.IfMatchVersion(r)
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return
+	}
+	if !handler.IsMergePatch(r) {
+		handler.WriteProblem(w, r, http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "error reading request body", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	responseBody, err := json.Marshal(updatedCar)
+	if !mergepatch.IsObject(body) {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "request body must be a JSON object")
+		return
+	}
+
+	patchedCar, err := h.service.PatchCar(id, body, version, ctx)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.SetETag(w, patchedCar.Version)
+	responseBody, err := json.Marshal(patchedCar)
 	if err != nil {
-		log.Println("error while marshalling: ", err)
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -149,7 +200,7 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
 	}
 }
 
@@ -157,15 +208,19 @@ func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
 	id := params["id"]
-	deletedCar, err := h.service.DeleteCar(id, ctx)
+	version, ok := handler.IfMatchVersion(r)
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return
+	}
+	deletedCar, err := h.service.DeleteCar(id, version, ctx)
 	if err != nil {
-		log.Println("Error while deleting the Car :", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(w, r, err)
 		return
 	}
 	responseBody, err := json.Marshal(deletedCar)
 	if err != nil {
-		log.Println("error while marshalling: ", err)
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -175,7 +230,80 @@ func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
 	}
 
 }
+
+func (h *CarHandler) ListDeletedCars(w http.ResponseWriter, r *http.Request) {
+	cars, err := h.service.ListDeletedCars(r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, cars)
+}
+
+func (h *CarHandler) RestoreCar(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	restoredCar, err := h.service.RestoreCar(id, r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.SetETag(w, restoredCar.Version)
+	handler.WriteJSON(w, r, http.StatusOK, restoredCar)
+}
+
+// PurgeCars permanently removes cars that have been in the trash for longer
+// than ?older_than (a duration such as 720h), defaulting to the standard
+// retention period.
+func (h *CarHandler) PurgeCars(w http.ResponseWriter, r *http.Request) {
+	retention, err := handler.RetentionParam(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.service.PurgeCars(retention, r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, result)
+}
+
+// GetCarByVIN looks a listed car up by its vehicle identification number.
+func (h *CarHandler) GetCarByVIN(w http.ResponseWriter, r *http.Request) {
+	vin := mux.Vars(r)["vin"]
+	car, err := h.service.GetCarByVIN(vin, r.URL.Query().Get("currency"), r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.SetETag(w, car.Version)
+	handler.WriteJSON(w, r, http.StatusOK, car)
+}
+
+// GetCarHistory lists every change made to the car, oldest first. The
+// history outlives the car, so it is still available after a purge.
+func (h *CarHandler) GetCarHistory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	entries, err := h.service.GetCarHistory(id, r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, entries)
+}
+
+// GetCarPrices lists the prices the car has been listed at, oldest first,
+// converted to ?currency when given.
+func (h *CarHandler) GetCarPrices(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	points, err := h.service.GetCarPrices(id, r.URL.Query().Get("currency"), r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, points)
+}
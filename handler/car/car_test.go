@@ -0,0 +1,153 @@
+package car
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+	carService "github.com/pranayyb/DriveThrough/service/car"
+	"github.com/pranayyb/DriveThrough/store/memory"
+)
+
+// civicID is one of the cars memory.Seed lists.
+const civicID = "c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3"
+
+func seededDB() *memory.DB {
+	db := memory.NewDB()
+	memory.Seed(db)
+	return db
+}
+
+// newTestRouter serves the car routes from a memory store.
+func newTestRouter(db *memory.DB) *mux.Router {
+	service := carService.NewCarService(memory.NewCarStore(db), memory.NewEngineStore(db), memory.NewExchangeRateStore(db), memory.NewTxManager(db))
+	h := NewCarHandler(service)
+	router := mux.NewRouter()
+	router.HandleFunc("/cars/import", h.ImportCars).Methods("POST")
+	router.HandleFunc("/cars/export", h.ExportCars).Methods("GET")
+	router.HandleFunc("/cars", h.ListCars).Methods("GET")
+	router.HandleFunc("/cars", h.CreateCar).Methods("POST")
+	router.HandleFunc("/cars/{id}", h.GetCarById).Methods("GET")
+	router.HandleFunc("/cars/{id}", h.UpdateCar).Methods("PUT")
+	router.HandleFunc("/cars/{id}", h.PatchCar).Methods("PATCH")
+	return router
+}
+
+func serve(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) handler.Problem {
+	t.Helper()
+	var problem handler.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decoding problem %q: %v", rec.Body, err)
+	}
+	return problem
+}
+
+func TestCarBodyDecodeErrors(t *testing.T) {
+	router := newTestRouter(seededDB())
+	tests := []struct {
+		name  string
+		body  string
+		want  int
+		field string
+	}{
+		{"malformed JSON", `{"name": "Civic",`, http.StatusBadRequest, ""},
+		{"not JSON", `name=Civic`, http.StatusBadRequest, ""},
+		{"year as a number", `{"name": "Civic", "year": 2023}`, http.StatusUnprocessableEntity, "year"},
+		{"displacement as a string", `{"name": "Civic", "engine": {"displacement": "2000"}}`, http.StatusUnprocessableEntity, "engine.displacement"},
+	}
+	for _, method := range []string{"POST", "PUT"} {
+		target := "/cars"
+		if method == "PUT" {
+			target = "/cars/" + civicID
+		}
+		for _, test := range tests {
+			t.Run(method+" "+test.name, func(t *testing.T) {
+				rec := serve(router, method, target, test.body)
+				if rec.Code != test.want {
+					t.Fatalf("got %d, want %d: %s", rec.Code, test.want, rec.Body)
+				}
+				problem := decodeProblem(t, rec)
+				if test.field != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != test.field) {
+					t.Errorf("got errors %+v, want one for %s", problem.Errors, test.field)
+				}
+			})
+		}
+	}
+}
+
+func TestPatchCar(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  string
+		want   int
+		fields []string
+	}{
+		{"changes a member", `{"name": "Civic Type R"}`, http.StatusOK, nil},
+		{"null removes the VIN", `{"vin": null}`, http.StatusOK, nil},
+		{"null may not remove a required member", `{"name": null}`, http.StatusUnprocessableEntity, []string{"name"}},
+		{"moves the car to another engine", `{"engine": {"engine_id": "f4a9c66b-8e38-419b-93c4-215d5cefb318"}}`, http.StatusOK, nil},
+		{"engine fields belong to the engine", `{"engine": {"displacement": 2500, "carRange": 650}}`, http.StatusUnprocessableEntity, []string{"engine.carRange", "engine.displacement"}},
+		{"engine_id may not be removed", `{"engine": {"engine_id": null}}`, http.StatusUnprocessableEntity, []string{"engine.engine_id"}},
+		{"nested price members merge", `{"price": {"amount": "26000.00"}}`, http.StatusOK, nil},
+		{"a body that is not an object", `["name", "Civic"]`, http.StatusBadRequest, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(newTestRouter(seededDB()), "PATCH", "/cars/"+civicID, test.patch)
+			if rec.Code != test.want {
+				t.Fatalf("got %d, want %d: %s", rec.Code, test.want, rec.Body)
+			}
+			if test.want != http.StatusUnprocessableEntity {
+				return
+			}
+			var fields []string
+			for _, fieldErr := range decodeProblem(t, rec).Errors {
+				fields = append(fields, fieldErr.Field)
+			}
+			if !slices.Equal(fields, test.fields) {
+				t.Errorf("got errors for %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestPatchDetachedCar(t *testing.T) {
+	db := seededDB()
+	const civicEngineID = "e1f86b1a-0873-4c19-bae2-fc60329d0140"
+	if _, err := memory.NewEngineStore(db).DeleteEngine(context.Background(), civicEngineID, 0, models.DeleteDetach); err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(db)
+
+	rec := serve(router, "PATCH", "/cars/"+civicID, `{"name": "Civic (no engine)"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patching a detached car got %d: %s", rec.Code, rec.Body)
+	}
+	var car models.Car
+	if err := json.Unmarshal(rec.Body.Bytes(), &car); err != nil {
+		t.Fatal(err)
+	}
+	if car.Name != "Civic (no engine)" || car.Engine.EngineID != uuid.Nil {
+		t.Errorf("got %q with engine %s, want the new name and still no engine", car.Name, car.Engine.EngineID)
+	}
+
+	rec = serve(router, "PATCH", "/cars/"+civicID, `{"engine": {"engine_id": "f4a9c66b-8e38-419b-93c4-215d5cefb318"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("attaching an engine to a detached car got %d: %s", rec.Code, rec.Body)
+	}
+}
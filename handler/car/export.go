@@ -0,0 +1,113 @@
+package car
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/export"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+var exportColumns = []any{
+	"id", "name", "year", "brand", "fuel_type", "vin", "price", "currency", "version", "created_at", "updated_at",
+	"engine_id", "displacement", "no_of_cylinders", "car_range",
+}
+
+// ExportCars streams every car matching the listing filters as a download
+// in ?format=csv (the default), ndjson or xlsx, with prices converted to
+// ?currency= if given. Pagination parameters are ignored. Once the first
+// row is sent the status can no longer change, so a failure midway is only
+// logged and leaves the download truncated.
+func (h *CarHandler) ExportCars(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "format must be csv, ndjson or xlsx")
+		return
+	}
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	filter.Cursor = ""
+	filter.Limit = 0
+
+	cursor, err := h.service.ExportCars(filter, r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	defer cursor.Close()
+
+	fileName := fmt.Sprintf("cars-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	w.WriteHeader(http.StatusOK)
+
+	if format == "ndjson" {
+		err = writeNDJSON(w, cursor)
+	} else {
+		err = writeTable(w, format, cursor)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error while exporting cars", "error", err)
+	}
+}
+
+func writeNDJSON(w io.Writer, cursor store.CarCursor) error {
+	encoder := json.NewEncoder(w)
+	for cursor.Next() {
+		if err := encoder.Encode(cursor.Car()); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func writeTable(w io.Writer, format string, cursor store.CarCursor) error {
+	var table export.TableWriter
+	if format == "xlsx" {
+		xlsx, err := export.NewXLSXWriter(w, "Cars")
+		if err != nil {
+			return err
+		}
+		table = xlsx
+	} else {
+		table = export.NewCSVWriter(w)
+	}
+	if err := table.WriteRow(exportColumns); err != nil {
+		return err
+	}
+	for cursor.Next() {
+		if err := table.WriteRow(exportRow(cursor.Car())); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return table.Close()
+}
+
+func exportRow(car models.Car) []any {
+	return []any{
+		car.ID, car.Name, car.Year, car.Brand, car.FuelType, car.VIN, export.Decimal(car.Price.Decimal()), car.Price.Currency, car.Version, car.CreatedAt, car.UpdatedAt,
+		car.Engine.EngineID, car.Engine.Displacement, car.Engine.NoOfCylinders, car.Engine.CarRange,
+	}
+}
@@ -0,0 +1,140 @@
+package car
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// seededCars are the names of the cars memory.Seed lists.
+var seededCars = []string{"BMW 3 Series", "Ford Mustang", "Honda Civic", "Toyota Corolla"}
+
+func exportBody(t *testing.T, query string) []byte {
+	t.Helper()
+	rec := serve(newTestRouter(seededDB()), "GET", "/cars/export?"+query, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment;") {
+		t.Errorf("got Content-Disposition %q, want an attachment", rec.Header().Get("Content-Disposition"))
+	}
+	return rec.Body.Bytes()
+}
+
+func exportCSV(t *testing.T, query string) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(exportBody(t, query))).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the CSV: %v", err)
+	}
+	return records
+}
+
+// names returns the sorted values of the name column.
+func names(records [][]string) []string {
+	var names []string
+	for _, record := range records[1:] {
+		names = append(names, record[1])
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestExportCSV(t *testing.T) {
+	records := exportCSV(t, "")
+	if len(records[0]) != len(exportColumns) || records[0][0] != "id" || records[0][6] != "price" {
+		t.Fatalf("got header %v", records[0])
+	}
+	if got := names(records); !slices.Equal(got, seededCars) {
+		t.Fatalf("exported %v, want %v", got, seededCars)
+	}
+	for _, record := range records[1:] {
+		if record[1] == "Honda Civic" && (record[6] != "25000.00" || record[7] != "USD" || record[12] != "2000") {
+			t.Errorf("the Civic is exported as %v", record)
+		}
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	var got []string
+	scanner := bufio.NewScanner(bytes.NewReader(exportBody(t, "format=ndjson")))
+	for scanner.Scan() {
+		var car models.Car
+		if err := json.Unmarshal(scanner.Bytes(), &car); err != nil {
+			t.Fatalf("line %q is not a car: %v", scanner.Text(), err)
+		}
+		got = append(got, car.Name)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, seededCars) {
+		t.Fatalf("exported %v, want %v", got, seededCars)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	body := exportBody(t, "format=xlsx")
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("the workbook is not a zip archive: %v", err)
+	}
+	sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("the workbook has no sheet: %v", err)
+	}
+	defer sheet.Close()
+	content, err := io.ReadAll(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range append(seededCars, "fuel_type") {
+		if !bytes.Contains(content, []byte(name)) {
+			t.Errorf("the sheet does not contain %q", name)
+		}
+	}
+}
+
+func TestExportFilters(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"brand=Honda,Toyota", []string{"Honda Civic", "Toyota Corolla"}},
+		{"fuel_type=Petrol&year_min=2024", []string{"Ford Mustang"}},
+		{"cylinders_min=6", []string{"Ford Mustang"}},
+		{"limit=1&sort=price&order=desc", seededCars},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := names(exportCSV(t, test.query)); !slices.Equal(got, test.want) {
+				t.Errorf("exported %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExportConvertsPrices(t *testing.T) {
+	for _, record := range exportCSV(t, "currency=EUR")[1:] {
+		if record[7] != "EUR" {
+			t.Errorf("%s is exported in %s, want EUR", record[1], record[7])
+		}
+	}
+}
+
+func TestExportRejectsBadParameters(t *testing.T) {
+	router := newTestRouter(seededDB())
+	if rec := serve(router, "GET", "/cars/export?format=pdf", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("an unknown format got %d, want 400", rec.Code)
+	}
+	if rec := serve(router, "GET", "/cars/export?year_min=new", ""); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("a malformed filter got %d, want 422", rec.Code)
+	}
+}
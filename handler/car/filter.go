@@ -0,0 +1,48 @@
+package car
+
+import (
+	"net/url"
+
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// parseCarFilter reads the listing filters from the query string. Multi-value
+// filters accept both repeated parameters and comma separated values.
+func parseCarFilter(query url.Values) (models.CarFilter, error) {
+	filter := models.CarFilter{
+		Brands:    handler.MultiValue(query, "brand"),
+		FuelTypes: handler.MultiValue(query, "fuel_type"),
+		SortBy:    query.Get("sort"),
+		Cursor:    query.Get("cursor"),
+		Currency:  query.Get("currency"),
+	}
+	var errs models.ValidationErrors
+	// price bounds are in the requested currency, or else the base one
+	boundsCurrency := filter.Currency
+	if boundsCurrency == "" {
+		boundsCurrency = models.BaseCurrency
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		errs = append(errs, models.NewValidationError("order", models.CodeInvalidChoice, "order must be asc or desc")...)
+	}
+	filter.YearMin = handler.IntParam(query, "year_min", &errs)
+	filter.YearMax = handler.IntParam(query, "year_max", &errs)
+	filter.Limit = handler.IntParam(query, "limit", &errs)
+	// an unsupported currency is reported by the filter's validation
+	if models.ValidCurrency(boundsCurrency) {
+		filter.PriceMin = handler.MoneyParam(query, "price_min", boundsCurrency, &errs)
+		filter.PriceMax = handler.MoneyParam(query, "price_max", boundsCurrency, &errs)
+	}
+	filter.DisplacementMin = handler.Int64Param(query, "displacement_min", &errs)
+	filter.DisplacementMax = handler.Int64Param(query, "displacement_max", &errs)
+	filter.CylindersMin = handler.Int64Param(query, "cylinders_min", &errs)
+	filter.CylindersMax = handler.Int64Param(query, "cylinders_max", &errs)
+	filter.PriceDroppedSince = handler.TimeParam(query, "price_dropped_since", &errs)
+	return filter, errs.Err()
+}
@@ -0,0 +1,54 @@
+package car
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestParseCarFilterReportsEveryBadParameter(t *testing.T) {
+	query := url.Values{
+		"order":               {"sideways"},
+		"limit":               {"ten"},
+		"year_min":            {"1990"},
+		"price_max":           {"12.345"},
+		"cylinders_min":       {"4.5"},
+		"price_dropped_since": {"last week"},
+	}
+	_, err := parseCarFilter(query)
+	var errs models.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want validation errors", err)
+	}
+	var fields []string
+	for _, fieldErr := range errs {
+		fields = append(fields, fieldErr.Field)
+	}
+	want := []string{"order", "limit", "price_max", "cylinders_min", "price_dropped_since"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("got errors for %v, want %v", fields, want)
+	}
+}
+
+func TestParseCarFilter(t *testing.T) {
+	query := url.Values{
+		"brand":     {"Honda,Toyota"},
+		"order":     {"desc"},
+		"limit":     {"10"},
+		"price_min": {"1000"},
+		"currency":  {"EUR"},
+	}
+	filter, err := parseCarFilter(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(filter.Brands, []string{"Honda", "Toyota"}) || !filter.Descending || filter.Limit != 10 {
+		t.Errorf("got %+v", filter)
+	}
+	if filter.PriceMin == nil || *filter.PriceMin != (models.Money{Amount: 100000, Currency: "EUR"}) {
+		t.Errorf("price_min is %v, want 1000.00 EUR", filter.PriceMin)
+	}
+}
@@ -0,0 +1,195 @@
+package car
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// maxImportBytes bounds the size of an import body.
+const maxImportBytes = 10 << 20
+
+// csvColumns are the columns of a CSV import, in any order. The engine is
+// referenced by id only; its specs are taken from the stored engine.
+var csvColumns = []string{"name", "year", "brand", "fuel_type", "price", "engine_id"}
+
+// optionalCSVColumns may be left out of a CSV import. Without a currency
+// column prices are in the base currency.
+var optionalCSVColumns = []string{"currency", "vin"}
+
+// ImportCars creates cars in bulk from a CSV (text/csv) or NDJSON
+// (application/x-ndjson) body. ?mode=atomic, the default, creates every row
+// or none; ?mode=best_effort creates the valid rows. The response reports
+// the outcome of each row by line number.
+func (h *CarHandler) ImportCars(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var rows []models.ImportRow
+	var err error
+	switch mediaType {
+	case "text/csv":
+		rows, err = readCSVRows(body)
+	case "application/x-ndjson", "application/ndjson":
+		rows, err = readNDJSONRows(body)
+	default:
+		handler.WriteProblem(w, r, http.StatusUnsupportedMediaType, "imports must be sent as text/csv or application/x-ndjson")
+		return
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			handler.WriteProblem(w, r, http.StatusRequestEntityTooLarge, "the import must not be larger than 10MB")
+			return
+		}
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.service.ImportCars(rows, r.URL.Query().Get("mode"), r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	status := http.StatusOK
+	if report.Mode == models.ImportModeAtomic && report.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	handler.WriteJSON(w, r, status, report)
+}
+
+// readCSVRows reads a CSV import with a header row. Malformed values are
+// reported on their row so the rest of the file can still be checked.
+func readCSVRows(body io.Reader) ([]models.ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the CSV has no header row")
+		}
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if !containsColumn(name) {
+			return nil, fmt.Errorf("unknown CSV column %q, expected: %s", name, strings.Join(append(csvColumns, optionalCSVColumns...), ", "))
+		}
+		columns[name] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("the CSV is missing the %q column", name)
+		}
+	}
+
+	var rows []models.ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err == nil {
+			line, _ := reader.FieldPos(0)
+			rows = append(rows, csvRow(line, record, columns))
+			continue
+		}
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) {
+			return nil, err
+		}
+		// the reader skips the rest of a malformed record, so the rows after
+		// it can still be checked
+		message := fmt.Sprintf("column %d: %v", parseErr.Column, parseErr.Err)
+		if errors.Is(err, csv.ErrFieldCount) {
+			message = fmt.Sprintf("expected %d columns, got %d", len(header), len(record))
+		}
+		rows = append(rows, models.ImportRow{
+			Line: parseErr.StartLine,
+			Err:  models.NewValidationError("", models.CodeInvalidFormat, message),
+		})
+	}
+}
+
+func csvRow(line int, record []string, columns map[string]int) models.ImportRow {
+	field := func(name string) string {
+		return strings.TrimSpace(record[columns[name]])
+	}
+	row := models.ImportRow{
+		Line: line,
+		Car: models.CarRequest{
+			Name:     field("name"),
+			Year:     field("year"),
+			Brand:    field("brand"),
+			FuelType: field("fuel_type"),
+		},
+	}
+	if _, ok := columns["vin"]; ok {
+		row.Car.VIN = field("vin")
+	}
+	var errs models.ValidationErrors
+	currency := models.BaseCurrency
+	if _, ok := columns["currency"]; ok {
+		currency = field("currency")
+	}
+	row.Car.Price.Currency = currency
+	if raw := field("price"); raw != "" && models.ValidCurrency(currency) {
+		price, err := models.ParseMoney(raw, currency)
+		if err != nil {
+			errs = append(errs, models.FieldError{Field: "price.amount", Code: models.CodeInvalidFormat, Message: err.Error()})
+		}
+		row.Car.Price = price
+	}
+	if raw := field("engine_id"); raw != "" {
+		engineID, err := uuid.Parse(raw)
+		if err != nil {
+			errs = append(errs, models.FieldError{Field: "engine.engine_id", Code: models.CodeInvalidFormat, Message: "engine_id must be a UUID"})
+		}
+		row.Car.Engine.EngineID = engineID
+	}
+	row.Err = errs.Err()
+	return row
+}
+
+func containsColumn(name string) bool {
+	for _, column := range append(csvColumns, optionalCSVColumns...) {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readNDJSONRows reads one car request per line, in the same shape as the
+// body of POST /cars. Blank lines are skipped.
+func readNDJSONRows(body io.Reader) ([]models.ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxImportBytes)
+	var rows []models.ImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		row := models.ImportRow{Line: line}
+		if err := json.Unmarshal(raw, &row.Car); err != nil {
+			row.Err = models.FromDecodeError(err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
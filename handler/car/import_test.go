@@ -0,0 +1,68 @@
+package car
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func importCSV(t *testing.T, mode, body string) (int, models.ImportReport) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/cars/import?mode="+mode, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+	newTestRouter(seededDB()).ServeHTTP(rec, req)
+	var report models.ImportReport
+	if rec.Code == http.StatusOK || rec.Code == http.StatusUnprocessableEntity {
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("decoding report %q: %v", rec.Body, err)
+		}
+	}
+	return rec.Code, report
+}
+
+func TestImportCSVReportsMalformedRecords(t *testing.T) {
+	const engineID = "e1f86b1a-0873-4c19-bae2-fc60329d0140"
+	body := "name,year,brand,fuel_type,price,engine_id\n" +
+		`"Civic"x,2023,Honda,Petrol,25000,` + engineID + "\n" +
+		"Accord,2023,Honda,Petrol,30000\n" +
+		"Jazz,2022,Honda,Petrol,18000," + engineID + "\n"
+
+	status, report := importCSV(t, models.ImportModeBestEffort, body)
+	if status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if report.Total != 3 || report.Created != 1 || report.Failed != 2 {
+		t.Fatalf("got %d rows, %d created and %d failed, want 3, 1 and 2", report.Total, report.Created, report.Failed)
+	}
+	for i, line := range []int{2, 3, 4} {
+		if report.Rows[i].Line != line {
+			t.Errorf("row %d is reported on line %d, want %d", i, report.Rows[i].Line, line)
+		}
+	}
+	if len(report.Rows[0].Errors) == 0 || report.Rows[0].Errors[0].Code != models.CodeInvalidFormat {
+		t.Errorf("the malformed record got errors %+v, want invalid_format", report.Rows[0].Errors)
+	}
+	if report.Rows[2].ID == nil {
+		t.Errorf("the valid row after the malformed ones was not created: %+v", report.Rows[2])
+	}
+}
+
+func TestImportCSVMalformedRecordFailsAtomicImport(t *testing.T) {
+	body := "name,year,brand,fuel_type,price,engine_id\n" + `"Civic"x,2023,Honda,Petrol,25000,e1f86b1a-0873-4c19-bae2-fc60329d0140` + "\n"
+	status, report := importCSV(t, models.ImportModeAtomic, body)
+	if status != http.StatusUnprocessableEntity || report.Created != 0 {
+		t.Fatalf("got %d with %d created, want 422 with none", status, report.Created)
+	}
+}
+
+func TestImportCSVRejectsMalformedHeader(t *testing.T) {
+	status, _ := importCSV(t, models.ImportModeAtomic, `"name"x,year`+"\n")
+	if status != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", status)
+	}
+}
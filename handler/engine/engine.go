@@ -2,12 +2,13 @@ package engine
 
 import (
 	"encoding/json"
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/mergepatch"
 	"github.com/pranayyb/DriveThrough/models"
 	"github.com/pranayyb/DriveThrough/service"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -28,14 +29,14 @@ func (e EngineHandler) GetEngineById(w http.ResponseWriter, r *http.Request) {
 
 	res, err := e.service.GetEngineById(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("error: ", err)
+		handler.WriteError(w, r, err)
 		return
 	}
+	handler.SetETag(w, res.Version)
 	body, err := json.Marshal(res)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("error: ", err)
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		return
 	}
 
@@ -44,15 +45,31 @@ func (e EngineHandler) GetEngineById(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(body)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
+	}
+}
+
+// ListEngines lists the engine catalog, with the number of cars using each
+// engine, so clients can find an engine to build a car with.
+func (e EngineHandler) ListEngines(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseEngineFilter(r.URL.Query())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
 	}
+	page, err := e.service.ListEngines(r.Context(), filter)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, page)
 }
 
 func (e EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("error: ", err)
+		slog.ErrorContext(ctx, "error reading request body", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -60,21 +77,19 @@ func (e EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 	var engineReq models.EngineRequest
 	err = json.Unmarshal(body, &engineReq)
 	if err != nil {
-		log.Println("error while un-marshalling request: ", err)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.WriteDecodeError(w, r, err)
 		return
 	}
 
 	createdEngine, err := e.service.CreateEngine(ctx, &engineReq)
 	if err != nil {
-		log.Println("error while creating engine: ", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	responseBody, err := json.Marshal(createdEngine)
 	if err != nil {
-		log.Println("error while marshalling: ", err)
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -83,7 +98,7 @@ func (e EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
 	}
 }
 
@@ -92,9 +107,14 @@ func (e *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
 	id := params["id"]
+	version, ok := handler.IfMatchVersion(r)
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("Err Reading Request body:", err)
+		slog.ErrorContext(ctx, "error reading request body", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -102,20 +122,19 @@ func (e *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 	var engineReq models.EngineRequest
 	err = json.Unmarshal(body, &engineReq)
 	if err != nil {
-		log.Println("Error while Un-marshalling Request body", err)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.WriteDecodeError(w, r, err)
 		return
 	}
 
-	updatedEngine, err := e.service.UpdateEngine(ctx, id, &engineReq)
+	updatedEngine, err := e.service.UpdateEngine(ctx, id, &engineReq, version)
 	if err != nil {
-		log.Println("Error while Updating the Engine :", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(w, r, err)
 		return
 	}
+	handler.SetETag(w, updatedEngine.Version)
 	responseBody, err := json.Marshal(updatedEngine)
 	if err != nil {
-		log.Println("error while marshalling: ", err)
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -124,37 +143,74 @@ func (e *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
 	}
 }
 
-func (e *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
+func (e *EngineHandler) PatchEngine(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
 	id := params["id"]
+	version, ok := handler.IfMatchVersion(r)
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return
+	}
+	if !handler.IsMergePatch(r) {
+		handler.WriteProblem(w, r, http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "error reading request body", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !mergepatch.IsObject(body) {
+		handler.WriteProblem(w, r, http.StatusBadRequest, "request body must be a JSON object")
+		return
+	}
 
-	deletedEngine, err := e.service.DeleteEngine(ctx, id)
+	patchedEngine, err := e.service.PatchEngine(ctx, id, body, version)
 	if err != nil {
-		log.Println("error while deleting engine : ", err)
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.SetETag(w, patchedEngine.Version)
+	responseBody, err := json.Marshal(patchedEngine)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while marshalling", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		response := map[string]string{"error": "Invalid ID or Engine not found!"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 
-	// check if engine was deleted succesfully
-	if deletedEngine.EngineID == uuid.Nil {
-		w.WriteHeader(http.StatusNotFound)
-		response := map[string]string{"error": "Engine Not Found"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
+	_, err = w.Write(responseBody)
+	if err != nil {
+		slog.ErrorContext(ctx, "error writing response", "error", err)
+	}
+}
+
+func (e *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	id := params["id"]
+	version, ok := handler.IfMatchVersion(r)
+	if !ok {
+		handler.WriteProblem(w, r, http.StatusPreconditionFailed, "If-Match does not match the current version")
+		return
+	}
+
+	deletedEngine, err := e.service.DeleteEngine(ctx, id, version, r.URL.Query().Get("strategy"))
+	if err != nil {
+		handler.WriteError(w, r, err)
 		return
 	}
 
 	jsonResponse, err := json.Marshal(deletedEngine)
 	if err != nil {
-		log.Println("Error while marshalling deleted engine response:", err)
+		slog.ErrorContext(ctx, "error while marshalling deleted engine response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		response := map[string]string{"error": "Internal server error"}
 		jsonResponse, _ := json.Marshal(response)
@@ -167,6 +223,66 @@ func (e *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(jsonResponse)
 	if err != nil {
-		log.Println("error writing response")
+		slog.ErrorContext(ctx, "error writing response", "error", err)
+	}
+}
+
+// GetEngineDependents previews the cars a delete of the engine would affect.
+func (e *EngineHandler) GetEngineDependents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	dependents, err := e.service.GetEngineDependents(r.Context(), id)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, dependents)
+}
+
+func (e *EngineHandler) ListDeletedEngines(w http.ResponseWriter, r *http.Request) {
+	engines, err := e.service.ListDeletedEngines(r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, engines)
+}
+
+func (e *EngineHandler) RestoreEngine(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	restoredEngine, err := e.service.RestoreEngine(r.Context(), id)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.SetETag(w, restoredEngine.Version)
+	handler.WriteJSON(w, r, http.StatusOK, restoredEngine)
+}
+
+// PurgeEngines permanently removes engines that have been in the trash for
+// longer than ?older_than (a duration such as 720h), defaulting to the
+// standard retention period.
+func (e *EngineHandler) PurgeEngines(w http.ResponseWriter, r *http.Request) {
+	retention, err := handler.RetentionParam(r)
+	if err != nil {
+		handler.WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := e.service.PurgeEngines(r.Context(), retention)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, result)
+}
+
+// GetEngineHistory lists every change made to the engine, oldest first. The
+// history outlives the engine, so it is still available after a purge.
+func (e *EngineHandler) GetEngineHistory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	entries, err := e.service.GetEngineHistory(r.Context(), id)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
 	}
+	handler.WriteJSON(w, r, http.StatusOK, entries)
 }
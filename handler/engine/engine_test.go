@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	engineService "github.com/pranayyb/DriveThrough/service/engine"
+	"github.com/pranayyb/DriveThrough/store/memory"
+)
+
+// engineID is one of the engines memory.Seed lists.
+const engineID = "e1f86b1a-0873-4c19-bae2-fc60329d0140"
+
+// newTestRouter serves the engine routes from a seeded memory store.
+func newTestRouter() *mux.Router {
+	db := memory.NewDB()
+	memory.Seed(db)
+	h := NewEngineHandler(engineService.NewEngineService(memory.NewEngineStore(db)))
+	router := mux.NewRouter()
+	router.HandleFunc("/engine", h.ListEngines).Methods("GET")
+	router.HandleFunc("/engine", h.CreateEngine).Methods("POST")
+	router.HandleFunc("/engine/{id}", h.UpdateEngine).Methods("PUT")
+	return router
+}
+
+func serve(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) handler.Problem {
+	t.Helper()
+	var problem handler.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decoding problem %q: %v", rec.Body, err)
+	}
+	return problem
+}
+
+func TestEngineBodyDecodeErrors(t *testing.T) {
+	router := newTestRouter()
+	tests := []struct {
+		name  string
+		body  string
+		want  int
+		field string
+	}{
+		{"malformed JSON", `{"displacement": 2000`, http.StatusBadRequest, ""},
+		{"displacement as a string", `{"displacement": "2000", "noOfCylinders": 4, "carRange": 600}`, http.StatusUnprocessableEntity, "displacement"},
+		{"fractional cylinders", `{"displacement": 2000, "noOfCylinders": 4.5, "carRange": 600}`, http.StatusUnprocessableEntity, "noOfCylinders"},
+	}
+	for _, method := range []string{"POST", "PUT"} {
+		target := "/engine"
+		if method == "PUT" {
+			target = "/engine/" + engineID
+		}
+		for _, test := range tests {
+			t.Run(method+" "+test.name, func(t *testing.T) {
+				rec := serve(router, method, target, test.body)
+				if rec.Code != test.want {
+					t.Fatalf("got %d, want %d: %s", rec.Code, test.want, rec.Body)
+				}
+				problem := decodeProblem(t, rec)
+				if test.field != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != test.field) {
+					t.Errorf("got errors %+v, want one for %s", problem.Errors, test.field)
+				}
+			})
+		}
+	}
+}
+
+func TestListEnginesReportsBadParameters(t *testing.T) {
+	rec := serve(newTestRouter(), "GET", "/engine?order=up&limit=many&range_max=far&sort=weight", "")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got %d, want 422: %s", rec.Code, rec.Body)
+	}
+	var fields []string
+	for _, fieldErr := range decodeProblem(t, rec).Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	if want := []string{"order", "limit", "range_max"}; !slices.Equal(fields, want) {
+		t.Errorf("got errors for %v, want %v", fields, want)
+	}
+
+	rec = serve(newTestRouter(), "GET", "/engine?sort=weight", "")
+	if rec.Code != http.StatusUnprocessableEntity || decodeProblem(t, rec).Errors[0].Field != "sort" {
+		t.Errorf("an unknown sort got %d: %s", rec.Code, rec.Body)
+	}
+}
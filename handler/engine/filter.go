@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"net/url"
+
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// parseEngineFilter reads the catalog filters from the query string.
+func parseEngineFilter(query url.Values) (models.EngineFilter, error) {
+	filter := models.EngineFilter{
+		SortBy: query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+	var errs models.ValidationErrors
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		errs = append(errs, models.NewValidationError("order", models.CodeInvalidChoice, "order must be asc or desc")...)
+	}
+	filter.Limit = handler.IntParam(query, "limit", &errs)
+	filter.DisplacementMin = handler.Int64Param(query, "displacement_min", &errs)
+	filter.DisplacementMax = handler.Int64Param(query, "displacement_max", &errs)
+	filter.CylindersMin = handler.Int64Param(query, "cylinders_min", &errs)
+	filter.CylindersMax = handler.Int64Param(query, "cylinders_max", &errs)
+	filter.RangeMin = handler.Int64Param(query, "range_min", &errs)
+	filter.RangeMax = handler.Int64Param(query, "range_max", &errs)
+	return filter, errs.Err()
+}
@@ -0,0 +1,70 @@
+package exchangerate
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/service"
+)
+
+// ExchangeRateHandler serves the admin endpoints that maintain the exchange
+// rates used to convert car prices.
+type ExchangeRateHandler struct {
+	service service.ExchangeRateServiceInterface
+}
+
+func NewExchangeRateHandler(service service.ExchangeRateServiceInterface) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		service: service,
+	}
+}
+
+func (h *ExchangeRateHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.service.ListRates(r.Context())
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, map[string]any{
+		"base_currency": models.BaseCurrency,
+		"rates":         rates,
+	})
+}
+
+func (h *ExchangeRateHandler) GetRate(w http.ResponseWriter, r *http.Request) {
+	rate, err := h.service.GetRate(r.Context(), mux.Vars(r)["currency"])
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, rate)
+}
+
+// SetRate creates or replaces the rate of the currency in the path, given
+// as {"rate": "0.92"}: the units of that currency one unit of the base
+// currency buys.
+func (h *ExchangeRateHandler) SetRate(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error reading request body", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var req models.ExchangeRateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		slog.WarnContext(r.Context(), "error while un-marshalling request", "error", err)
+		handler.WriteProblem(w, r, http.StatusBadRequest, "request body is not valid JSON")
+		return
+	}
+	rate, err := h.service.SetRate(r.Context(), mux.Vars(r)["currency"], &req)
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, rate)
+}
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+)
+
+// IsMergePatch reports whether the request body is declared as a JSON merge
+// patch. Plain application/json (or no content type) is accepted as well.
+func IsMergePatch(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/merge-patch+json" || mediaType == "application/json"
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// Problem is an RFC 7807 problem details body.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Errors lists every invalid field of a 422 response.
+	Errors []models.FieldError `json:"errors,omitempty"`
+	// Dependents lists the resources that block a delete with a 409.
+	Dependents []uuid.UUID `json:"dependents,omitempty"`
+}
+
+// WriteError maps a domain error onto its HTTP status and writes it as a
+// problem. Anything unrecognised is logged and reported as a 500 without
+// leaking its message to the client.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrUnauthenticated):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="DriveThrough"`)
+		WriteProblem(w, r, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, models.ErrForbidden):
+		WriteProblem(w, r, http.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrInvalidID):
+		WriteProblem(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrNotFound):
+		WriteProblem(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrPreconditionFailed):
+		WriteProblem(w, r, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, models.ErrConflict):
+		problem := newProblem(r, http.StatusConflict, err.Error())
+		var dependentsErr *models.DependentsError
+		if errors.As(err, &dependentsErr) {
+			problem.Dependents = dependentsErr.Dependents
+		}
+		writeProblem(w, r, problem)
+	case errors.Is(err, models.ErrValidation):
+		problem := newProblem(r, http.StatusUnprocessableEntity, "the request has invalid fields")
+		var validationErrs models.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			problem.Errors = validationErrs
+		}
+		writeProblem(w, r, problem)
+	default:
+		slog.ErrorContext(r.Context(), "unexpected error", "error", err)
+		WriteProblem(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// WriteDecodeError reports a request body that could not be decoded.
+// Malformed JSON is a 400; a well-formed document with a field of the
+// wrong type is a 422 naming the field, like any other invalid field.
+func WriteDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.WarnContext(r.Context(), "error while un-marshalling request", "error", err)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		WriteProblem(w, r, http.StatusBadRequest, "request body is not valid JSON")
+		return
+	}
+	WriteError(w, r, models.FromDecodeError(err))
+}
+
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblem(w, r, newProblem(r, status, detail))
+}
+
+func newProblem(r *http.Request, status int, detail string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error while marshalling problem", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error writing response", "error", err)
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestWriteError(t *testing.T) {
+	dependent := uuid.New()
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+	}{
+		{"unauthenticated", models.NewUnauthenticatedError("token expired"), http.StatusUnauthorized, "token expired"},
+		{"forbidden", models.NewForbiddenError("editors only"), http.StatusForbidden, "editors only"},
+		{"invalid id", models.NewInvalidIDError("car", "x"), http.StatusBadRequest, ""},
+		{"not found", models.NewNotFoundError("car", "1"), http.StatusNotFound, ""},
+		{"precondition failed", models.NewPreconditionFailedError("car", "1"), http.StatusPreconditionFailed, ""},
+		{"conflict", models.NewConflictError("restore the engine first"), http.StatusConflict, "restore the engine first"},
+		{"dependents", models.NewDependentsError("engine", "1", []uuid.UUID{dependent}), http.StatusConflict, ""},
+		{"duplicate", models.NewDuplicateError("car", "vin", "1M8GDM9AXKP042788"), http.StatusConflict, ""},
+		{"validation", models.NewValidationError("name", models.CodeRequired, "name is required"), http.StatusUnprocessableEntity, "the request has invalid fields"},
+		{"wrapped", errors.Join(errors.New("context"), models.NewNotFoundError("engine", "2")), http.StatusNotFound, ""},
+		{"unexpected", errors.New("connection refused"), http.StatusInternalServerError, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteError(rec, httptest.NewRequest("GET", "/cars/1", nil), test.err)
+
+			if rec.Code != test.status {
+				t.Fatalf("got status %d, want %d", rec.Code, test.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("got Content-Type %q, want application/problem+json", got)
+			}
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Status != test.status || problem.Title != http.StatusText(test.status) || problem.Instance != "/cars/1" {
+				t.Errorf("got problem %+v", problem)
+			}
+			if test.detail != "" && problem.Detail != test.detail {
+				t.Errorf("got detail %q, want %q", problem.Detail, test.detail)
+			}
+		})
+	}
+}
+
+func TestWriteErrorDetails(t *testing.T) {
+	t.Run("unexpected errors are not leaked", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteError(rec, httptest.NewRequest("GET", "/cars", nil), errors.New("pq: password authentication failed"))
+		var problem Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if problem.Detail != "" {
+			t.Errorf("got detail %q, want none", problem.Detail)
+		}
+	})
+	t.Run("unauthenticated asks for a bearer token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteError(rec, httptest.NewRequest("GET", "/cars", nil), models.NewUnauthenticatedError("missing token"))
+		if got := rec.Header().Get("WWW-Authenticate"); got == "" {
+			t.Error("no WWW-Authenticate header")
+		}
+	})
+	t.Run("validation lists the fields", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := append(models.NewValidationError("name", models.CodeRequired, "name is required"),
+			models.NewValidationError("engine.displacement", models.CodeOutOfRange, "displacement must be greater than 0")...)
+		WriteError(rec, httptest.NewRequest("POST", "/cars", nil), err)
+		var problem Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if len(problem.Errors) != 2 || problem.Errors[1].Field != "engine.displacement" {
+			t.Errorf("got errors %+v", problem.Errors)
+		}
+	})
+	t.Run("dependents are listed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		dependents := []uuid.UUID{uuid.New(), uuid.New()}
+		WriteError(rec, httptest.NewRequest("DELETE", "/engine/1", nil), models.NewDependentsError("engine", "1", dependents))
+		var problem Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if len(problem.Dependents) != 2 || problem.Dependents[0] != dependents[0] {
+			t.Errorf("got dependents %v, want %v", problem.Dependents, dependents)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// MultiValue reads a multi-value parameter given either as repeated
+// parameters or as comma separated values.
+func MultiValue(query url.Values, key string) []string {
+	var values []string
+	for _, raw := range query[key] {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// The parameter readers below add a field error named after the parameter
+// to errs when its value is malformed, so every bad parameter of a query is
+// reported at once like the fields of a body.
+
+func IntParam(query url.Values, key string, errs *models.ValidationErrors) int {
+	raw := query.Get(key)
+	if raw == "" {
+		return 0
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		*errs = append(*errs, models.NewValidationError(key, models.CodeInvalidFormat, key+" must be a whole number")...)
+		return 0
+	}
+	return value
+}
+
+func Int64Param(query url.Values, key string, errs *models.ValidationErrors) *int64 {
+	raw := query.Get(key)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		*errs = append(*errs, models.NewValidationError(key, models.CodeInvalidFormat, key+" must be a whole number")...)
+		return nil
+	}
+	return &value
+}
+
+// MoneyParam reads a decimal amount in the given currency.
+func MoneyParam(query url.Values, key string, currency string, errs *models.ValidationErrors) *models.Money {
+	raw := query.Get(key)
+	if raw == "" {
+		return nil
+	}
+	value, err := models.ParseMoney(raw, currency)
+	if err != nil {
+		*errs = append(*errs, models.NewValidationError(key, models.CodeInvalidFormat, key+": "+err.Error())...)
+		return nil
+	}
+	return &value
+}
+
+// TimeParam reads an RFC 3339 timestamp, or a date taken as midnight UTC.
+func TimeParam(query url.Values, key string, errs *models.ValidationErrors) *time.Time {
+	raw := query.Get(key)
+	if raw == "" {
+		return nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		value, err = time.Parse(time.DateOnly, raw)
+	}
+	if err != nil {
+		*errs = append(*errs, models.NewValidationError(key, models.CodeInvalidFormat, key+" must be a date such as 2024-01-31 or an RFC 3339 timestamp")...)
+		return nil
+	}
+	return &value
+}
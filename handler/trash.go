@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// RetentionParam reads the ?older_than purge retention, falling back to
+// models.DefaultTrashRetention.
+func RetentionParam(r *http.Request) (time.Duration, error) {
+	raw := r.URL.Query().Get("older_than")
+	if raw == "" {
+		return models.DefaultTrashRetention, nil
+	}
+	retention, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, errors.New("older_than must be a duration such as 720h")
+	}
+	return retention, nil
+}
@@ -0,0 +1,20 @@
+package vin
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+	decoder "github.com/pranayyb/DriveThrough/vin"
+)
+
+// Decode tells what the VIN in the path says about a vehicle, from the
+// bundled manufacturer table alone. It needs no car to be listed with it.
+func Decode(w http.ResponseWriter, r *http.Request) {
+	decoded, err := decoder.Decode(mux.Vars(r)["vin"])
+	if err != nil {
+		handler.WriteError(w, r, err)
+		return
+	}
+	handler.WriteJSON(w, r, http.StatusOK, decoded)
+}
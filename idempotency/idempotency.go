@@ -0,0 +1,142 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/pranayyb/DriveThrough/audit"
+	"github.com/pranayyb/DriveThrough/auth"
+	"github.com/pranayyb/DriveThrough/handler"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+const (
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Handler makes a create endpoint safe to retry. The first request made
+// with an Idempotency-Key runs normally and its response is stored; a retry
+// with the same key and body gets that response replayed instead of running
+// again. Reusing a key with a different body is rejected with 422, and a
+// retry that arrives while the first request is still running gets 409,
+// unless the first request has outlived models.IdempotencyLease.
+// Keys belong to the caller that sent them, so one caller never gets the
+// response to another's request.
+// Requests without the header are passed through untouched.
+func Handler(keys store.IdempotencyStoreInterface, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			handler.WriteProblem(w, r, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error reading request body", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+
+		ctx := r.Context()
+		record := models.IdempotencyRecord{
+			Principal:   principalKey(r),
+			Scope:       r.Method + " " + r.URL.Path,
+			Key:         key,
+			RequestHash: hex.EncodeToString(hash[:]),
+		}
+		existing, reserved, err := keys.Reserve(ctx, record)
+		if err != nil {
+			handler.WriteError(w, r, err)
+			return
+		}
+		if !reserved {
+			replay(w, r, existing, record.RequestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		// server errors are not final, so the client may retry them for real
+		if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+			if err := keys.Release(ctx, record.Principal, record.Scope, record.Key); err != nil {
+				slog.ErrorContext(ctx, "error while releasing idempotency key", "error", err)
+			}
+			return
+		}
+		record.Status = recorder.status
+		record.ContentType = recorder.Header().Get("Content-Type")
+		record.Body = recorder.body.Bytes()
+		if err := keys.Complete(ctx, record); err != nil {
+			slog.ErrorContext(ctx, "error while storing idempotent response", "error", err)
+		}
+	}
+}
+
+// principalKey identifies the caller, so that a key one caller chose never
+// replays the response of another.
+func principalKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		return principal.Key()
+	}
+	// authentication is disabled, the actor is all there is to tell
+	// callers apart
+	return "actor:" + audit.ActorFrom(r.Context())
+}
+
+func replay(w http.ResponseWriter, r *http.Request, existing models.IdempotencyRecord, requestHash string) {
+	if existing.RequestHash != requestHash {
+		handler.WriteProblem(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request body")
+		return
+	}
+	if !existing.Completed() {
+		handler.WriteProblem(w, r, http.StatusConflict, "a request with this Idempotency-Key is still being processed")
+		return
+	}
+	if existing.ContentType != "" {
+		w.Header().Set("Content-Type", existing.ContentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(existing.Status)
+	if _, err := w.Write(existing.Body); err != nil {
+		slog.ErrorContext(r.Context(), "error writing response", "error", err)
+	}
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,141 @@
+package idempotency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/auth"
+	"github.com/pranayyb/DriveThrough/store/memory"
+)
+
+// countingHandler creates a resource on every call and answers with how
+// many it has created so far.
+func countingHandler(calls *atomic.Int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"created":%d}`, n)
+	}
+}
+
+func post(h http.Handler, subject, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/cars", strings.NewReader(body))
+	req.Header.Set(Header, key)
+	if subject != "" {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: subject, Role: auth.RoleEditor}))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplay(t *testing.T) {
+	var calls atomic.Int64
+	h := Handler(memory.NewIdempotencyStore(), countingHandler(&calls))
+
+	first := post(h, "alice", "k1", `{"name":"Civic"}`)
+	retry := post(h, "alice", "k1", `{"name":"Civic"}`)
+	if calls.Load() != 1 {
+		t.Fatalf("the handler ran %d times, want once", calls.Load())
+	}
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() || retry.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("the retry got %d %q, want the first response %d %q", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get(ReplayedHeader) != "true" || first.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("only the retry should be marked as replayed")
+	}
+
+	if rec := post(h, "alice", "k2", `{"name":"Civic"}`); rec.Body.String() != `{"created":2}` {
+		t.Errorf("a new key got %q, want a new resource", rec.Body)
+	}
+}
+
+func TestKeyReusedWithDifferentBody(t *testing.T) {
+	var calls atomic.Int64
+	h := Handler(memory.NewIdempotencyStore(), countingHandler(&calls))
+
+	post(h, "alice", "k1", `{"name":"Civic"}`)
+	rec := post(h, "alice", "k1", `{"name":"Accord"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got %d, want 422", rec.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("the handler ran %d times, want once", calls.Load())
+	}
+}
+
+func TestRetryWhileInFlight(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int64
+	h := Handler(memory.NewIdempotencyStore(), func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		countingHandler(&calls)(w, r)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(h, "alice", "k1", `{}`) }()
+	<-entered
+	if rec := post(h, "alice", "k1", `{}`); rec.Code != http.StatusConflict {
+		t.Errorf("a retry while the first request runs got %d, want 409", rec.Code)
+	}
+	close(release)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Fatalf("the first request got %d", rec.Code)
+	}
+	if rec := post(h, "alice", "k1", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("a retry after the first request completed got %d, want its response replayed", rec.Code)
+	}
+}
+
+func TestKeysBelongToTheirPrincipal(t *testing.T) {
+	var calls atomic.Int64
+	h := Handler(memory.NewIdempotencyStore(), countingHandler(&calls))
+
+	alice := post(h, "alice", "k1", `{}`)
+	bob := post(h, "bob", "k1", `{}`)
+	if calls.Load() != 2 {
+		t.Fatalf("the handler ran %d times, want once for each caller", calls.Load())
+	}
+	if bob.Body.String() == alice.Body.String() || bob.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("bob got alice's response %q", bob.Body)
+	}
+	if rec := post(h, "bob", "k1", `{"different":true}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("bob reusing his own key with another body got %d, want 422", rec.Code)
+	}
+}
+
+func TestServerErrorsReleaseTheKey(t *testing.T) {
+	var calls atomic.Int64
+	failing := true
+	h := Handler(memory.NewIdempotencyStore(), func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		countingHandler(&calls)(w, r)
+	})
+
+	post(h, "alice", "k1", `{}`)
+	failing = false
+	if rec := post(h, "alice", "k1", `{}`); rec.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("a retry after a server error got %d after %d calls, want it to run again", rec.Code, calls.Load())
+	}
+}
+
+func TestRequestsWithoutKeyPassThrough(t *testing.T) {
+	var calls atomic.Int64
+	h := Handler(memory.NewIdempotencyStore(), countingHandler(&calls))
+	for range 2 {
+		post(h, "alice", "", `{}`)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("the handler ran %d times, want twice", calls.Load())
+	}
+}
@@ -0,0 +1,170 @@
+// Package logging writes structured JSON logs and tags every line logged
+// while serving a request with its request ID, method and route.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// RequestIDHeader carries the request ID, taken from the client or a proxy
+// in front of the API when present and generated otherwise.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a request ID taken from the client.
+const maxRequestIDLength = 128
+
+// ParseLevel reads a level such as debug, info, warn or error. An empty
+// text means info.
+func ParseLevel(text string) (slog.Level, error) {
+	var level slog.Level
+	if text == "" {
+		return slog.LevelInfo, nil
+	}
+	err := level.UnmarshalText([]byte(text))
+	return level, err
+}
+
+// New returns a logger writing JSON lines at the given level or above,
+// adding the request fields of the context to each line logged with one.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// Request is what is known about the request being served.
+type Request struct {
+	ID     string
+	Method string
+	// Route is the path template the router matched, e.g. /cars/{id}, or
+	// the path when no route matched.
+	Route string
+}
+
+type requestKey struct{}
+
+func WithRequest(ctx context.Context, request Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+func RequestFrom(ctx context.Context) (Request, bool) {
+	request, ok := ctx.Value(requestKey{}).(Request)
+	return request, ok
+}
+
+// RequestID returns the ID of the request being served, or "" outside of
+// one.
+func RequestID(ctx context.Context) string {
+	request, _ := RequestFrom(ctx)
+	return request.ID
+}
+
+// contextHandler adds the request fields of the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if request, ok := RequestFrom(ctx); ok {
+		record.AddAttrs(
+			slog.String("request_id", request.ID),
+			slog.String("method", request.Method),
+			slog.String("route", request.Route),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Middleware gives each request an ID, echoed in the X-Request-ID response
+// header, and puts it in the context with the method and route so they are
+// logged with every line. Once the request is served it logs its status
+// and latency. It should be the first middleware, so requests rejected by
+// the others are logged too. A mux.Router skips its middleware when no
+// route matches, so its NotFoundHandler and MethodNotAllowedHandler need
+// wrapping as well.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		request := Request{
+			ID:     requestID(r),
+			Method: r.Method,
+			Route:  r.URL.Path,
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				request.Route = template
+			}
+		}
+		w.Header().Set(RequestIDHeader, request.ID)
+		ctx := WithRequest(r.Context(), request)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(ctx, level, "request completed",
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
+
+// requestID returns the request ID sent by the client if it is usable, or
+// else a new one.
+func requestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength || strings.IndexFunc(id, notPrintable) >= 0 {
+		return uuid.NewString()
+	}
+	return id
+}
+
+func notPrintable(c rune) bool {
+	return c < '!' || c > '~'
+}
+
+// statusRecorder passes the response through while noting its status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
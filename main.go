@@ -1,78 +1,370 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/pranayyb/DriveThrough/audit"
+	"github.com/pranayyb/DriveThrough/auth"
 	"github.com/pranayyb/DriveThrough/driver"
+	"github.com/pranayyb/DriveThrough/handler"
+	apiKeyHandler "github.com/pranayyb/DriveThrough/handler/apikey"
 	carHandler "github.com/pranayyb/DriveThrough/handler/car"
 	engineHandler "github.com/pranayyb/DriveThrough/handler/engine"
+	exchangeRateHandler "github.com/pranayyb/DriveThrough/handler/exchangerate"
+	vinHandler "github.com/pranayyb/DriveThrough/handler/vin"
+	"github.com/pranayyb/DriveThrough/idempotency"
+	"github.com/pranayyb/DriveThrough/logging"
+	"github.com/pranayyb/DriveThrough/metrics"
+	"github.com/pranayyb/DriveThrough/migrations"
+	"github.com/pranayyb/DriveThrough/ratelimit"
+	"github.com/pranayyb/DriveThrough/service"
+	apiKeyService "github.com/pranayyb/DriveThrough/service/apikey"
 	carService "github.com/pranayyb/DriveThrough/service/car"
 	engineService "github.com/pranayyb/DriveThrough/service/engine"
+	exchangeRateService "github.com/pranayyb/DriveThrough/service/exchangerate"
+	"github.com/pranayyb/DriveThrough/store"
+	apiKeyStore "github.com/pranayyb/DriveThrough/store/apikey"
 	carStore "github.com/pranayyb/DriveThrough/store/car"
 	engineStore "github.com/pranayyb/DriveThrough/store/engine"
+	exchangeRateStore "github.com/pranayyb/DriveThrough/store/exchangerate"
+	idempotencyStore "github.com/pranayyb/DriveThrough/store/idempotency"
+	"github.com/pranayyb/DriveThrough/store/memory"
+	"github.com/pranayyb/DriveThrough/store/metered"
+	"github.com/pranayyb/DriveThrough/store/uow"
 )
 
 func main() {
-	err := godotenv.Load()
+	// .env is optional: docker-compose and the memory backend configure
+	// everything through the environment.
+	envErr := godotenv.Load()
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
 	if err != nil {
-		log.Fatal("error loading .env file")
+		fatal("LOG_LEVEL must be one of debug, info, warn or error", "error", err)
+	}
+	slog.SetDefault(logging.New(os.Stdout, level))
+	if envErr != nil {
+		slog.Info("no .env file loaded, using process environment")
 	}
 
-	driver.InitDB()
-	defer driver.CloseDB()
+	stores := openStores()
+	defer stores.close()
 
-	db := driver.GetDB()
+	registry := metrics.NewRegistry()
+	storeMetrics := metrics.NewStoreMetrics(registry)
+	stores.cars = metered.NewCarStore(stores.cars, storeMetrics)
+	stores.engines = metered.NewEngineStore(stores.engines, storeMetrics)
+	if stores.db != nil {
+		metrics.RegisterDBStats(registry, stores.db)
+	}
 
-	carStore := carStore.New(db)
-	carService := carService.NewCarService(carStore)
+	carService := carService.NewCarService(stores.cars, stores.engines, stores.rates, stores.tx)
 	carHandler := carHandler.NewCarHandler(carService)
 
-	engineStore := engineStore.New(db)
-	engineService := engineService.NewEngineService(engineStore)
+	engineService := engineService.NewEngineService(stores.engines)
 	engineHandler := engineHandler.NewEngineHandler(engineService)
 
-	router := mux.NewRouter()
+	exchangeRateService := exchangeRateService.NewExchangeRateService(stores.rates)
+	exchangeRateHandler := exchangeRateHandler.NewExchangeRateHandler(exchangeRateService)
 
-	schemaFile := "store/schema.sql"
-	if err := executeSchemaFile(db, schemaFile); err != nil {
-		log.Fatal("error while executing schema file")
+	apiKeyService := apiKeyService.NewAPIKeyService(stores.apiKeys)
+	apiKeyHandler := apiKeyHandler.NewAPIKeyHandler(apiKeyService)
+
+	registerBusinessMetrics(registry, carService)
+
+	router := mux.NewRouter()
+	router.Use(logging.Middleware)
+	httpMetrics := metrics.HTTPMiddleware(registry)
+	router.Use(httpMetrics)
+	limiter := ratelimit.NewMemoryLimiter()
+	if policy, ok := rateLimitPolicy("RATE_LIMIT_IP", "600/1m"); ok {
+		router.Use(ratelimit.IPMiddleware(limiter, policy))
 	}
+	if authenticators := openAuthenticators(apiKeyService); authenticators != nil {
+		router.Use(auth.Middleware(authenticators...))
+	} else {
+		slog.Warn("AUTH_DISABLED is set, requests are not authenticated")
+		router.Use(audit.Middleware)
+	}
+	router.Use(ratelimit.Middleware(limiter, rateLimitPolicies()))
+	router.NotFoundHandler = unmatched(http.StatusNotFound, logging.Middleware, httpMetrics)
+	router.MethodNotAllowedHandler = unmatched(http.StatusMethodNotAllowed, logging.Middleware, httpMetrics)
 
+	router.HandleFunc("/cars/import", carHandler.ImportCars).Methods("POST")
+	router.HandleFunc("/cars/export", carHandler.ExportCars).Methods("GET")
+	router.HandleFunc("/cars/trash", carHandler.ListDeletedCars).Methods("GET")
+	router.HandleFunc("/cars/trash", carHandler.PurgeCars).Methods("DELETE")
+	router.HandleFunc("/cars/vin/{vin}", carHandler.GetCarByVIN).Methods("GET")
+	router.HandleFunc("/cars/{id}/restore", carHandler.RestoreCar).Methods("POST")
+	router.HandleFunc("/cars/{id}/history", carHandler.GetCarHistory).Methods("GET")
+	router.HandleFunc("/cars/{id}/prices", carHandler.GetCarPrices).Methods("GET")
 	router.HandleFunc("/cars/{id}", carHandler.GetCarById).Methods("GET")
-	router.HandleFunc("/cars", carHandler.GetCarByBrand).Methods("GET")
-	router.HandleFunc("/cars", carHandler.CreateCar).Methods("POST")
+	router.HandleFunc("/cars", carHandler.ListCars).Methods("GET")
+	router.HandleFunc("/cars", idempotency.Handler(stores.idempotency, carHandler.CreateCar)).Methods("POST")
 	router.HandleFunc("/cars/{id}", carHandler.UpdateCar).Methods("PUT")
+	router.HandleFunc("/cars/{id}", carHandler.PatchCar).Methods("PATCH")
 	router.HandleFunc("/cars/{id}", carHandler.DeleteCar).Methods("DELETE")
 
+	router.HandleFunc("/engine/trash", engineHandler.ListDeletedEngines).Methods("GET")
+	router.HandleFunc("/engine/trash", engineHandler.PurgeEngines).Methods("DELETE")
+	router.HandleFunc("/engine/{id}/restore", engineHandler.RestoreEngine).Methods("POST")
+	router.HandleFunc("/engine/{id}/history", engineHandler.GetEngineHistory).Methods("GET")
+	router.HandleFunc("/engine/{id}/dependents", engineHandler.GetEngineDependents).Methods("GET")
 	router.HandleFunc("/engine/{id}", engineHandler.GetEngineById).Methods("GET")
-	router.HandleFunc("/engine", engineHandler.CreateEngine).Methods("POST")
+	router.HandleFunc("/engine", engineHandler.ListEngines).Methods("GET")
+	router.HandleFunc("/engine", idempotency.Handler(stores.idempotency, engineHandler.CreateEngine)).Methods("POST")
 	router.HandleFunc("/engine/{id}", engineHandler.UpdateEngine).Methods("PUT")
+	router.HandleFunc("/engine/{id}", engineHandler.PatchEngine).Methods("PATCH")
 	router.HandleFunc("/engine/{id}", engineHandler.DeleteEngine).Methods("DELETE")
 
+	router.HandleFunc("/admin/exchange-rates", exchangeRateHandler.ListRates).Methods("GET")
+	router.HandleFunc("/admin/exchange-rates/{currency}", exchangeRateHandler.GetRate).Methods("GET")
+	router.HandleFunc("/admin/exchange-rates/{currency}", exchangeRateHandler.SetRate).Methods("PUT")
+
+	router.HandleFunc("/admin/api-keys", apiKeyHandler.ListKeys).Methods("GET")
+	router.HandleFunc("/admin/api-keys", apiKeyHandler.IssueKey).Methods("POST")
+	router.HandleFunc("/admin/api-keys/{id}", apiKeyHandler.RevokeKey).Methods("DELETE")
+
+	router.HandleFunc("/vin/{vin}/decode", vinHandler.Decode).Methods("GET")
+
+	// /metrics is served next to the router rather than on it, so scrapes
+	// need no credentials and are neither rate limited nor counted.
+	root := http.NewServeMux()
+	root.Handle("GET /metrics", registry.Handler())
+	root.Handle("/", router)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Server listening on port: %s", addr)
-	log.Fatal(http.ListenAndServe(addr, router))
+	slog.Info("server listening", "addr", addr)
+	err = http.ListenAndServe(addr, root)
+	fatal("server stopped", "error", err)
 
 }
 
-func executeSchemaFile(db *sql.DB, schemaFile string) error {
-	sqlFile, err := os.ReadFile(schemaFile)
+type stores struct {
+	cars        store.CarStoreInterface
+	engines     store.EngineStoreInterface
+	idempotency store.IdempotencyStoreInterface
+	apiKeys     store.APIKeyStoreInterface
+	rates       store.ExchangeRateStoreInterface
+	tx          store.TxManager
+	// db is the database of the postgres backend, nil for the memory one.
+	db    *sql.DB
+	close func()
+}
+
+// openStores builds the stores for the backend selected by STORE_BACKEND.
+// The memory backend is seeded with demo data and needs no database.
+func openStores() stores {
+	backend := os.Getenv("STORE_BACKEND")
+	switch backend {
+	case "memory":
+		slog.Info("using in-memory store with seed data")
+		db := memory.NewDB()
+		memory.Seed(db)
+		return stores{
+			cars:        memory.NewCarStore(db),
+			engines:     memory.NewEngineStore(db),
+			idempotency: memory.NewIdempotencyStore(),
+			apiKeys:     memory.NewAPIKeyStore(),
+			rates:       memory.NewExchangeRateStore(db),
+			tx:          memory.NewTxManager(db),
+			close:       func() {},
+		}
+	case "", "postgres":
+		driver.InitDB()
+		db := driver.GetDB()
+
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			err := runMigrate(db, os.Args[2:])
+			driver.CloseDB()
+			if err != nil {
+				fatal("migrate failed", "error", err)
+			}
+			os.Exit(0)
+		}
+
+		if os.Getenv("AUTO_MIGRATE") != "false" {
+			if err := runMigrate(db, []string{"up"}); err != nil {
+				fatal("error while migrating database", "error", err)
+			}
+		}
+		return stores{
+			cars:        carStore.New(db),
+			engines:     engineStore.New(db),
+			idempotency: idempotencyStore.New(db),
+			apiKeys:     apiKeyStore.New(db),
+			rates:       exchangeRateStore.New(db),
+			tx:          uow.New(db),
+			db:          db,
+			close:       driver.CloseDB,
+		}
+	default:
+		fatal("unknown STORE_BACKEND, expected postgres or memory", "backend", backend)
+		return stores{}
+	}
+}
+
+// openAuthenticators builds the authenticators configured in the
+// environment: JWTs signed with JWT_HS256_SECRET and/or the key in
+// JWT_RS256_PUBLIC_KEY_FILE, with optional JWT_ISSUER and JWT_AUDIENCE,
+// and API keys issued through the admin endpoints. It returns nil when
+// AUTH_DISABLED is true, in which case the audit actor is taken from
+// X-Actor.
+func openAuthenticators(keys auth.KeyVerifier) []auth.Authenticator {
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		return nil
+	}
+	config := auth.JWTConfig{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+	}
+	if secret := os.Getenv("JWT_HS256_SECRET"); secret != "" {
+		config.HS256Secret = []byte(secret)
+	}
+	if path := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fatal("error while reading JWT_RS256_PUBLIC_KEY_FILE", "error", err)
+		}
+		if config.RS256PublicKey, err = auth.ParseRSAPublicKey(data); err != nil {
+			fatal("error while parsing JWT_RS256_PUBLIC_KEY_FILE", "error", err)
+		}
+	}
+	jwt, err := auth.NewJWTAuthenticator(config)
 	if err != nil {
-		return err
+		fatal("set JWT_HS256_SECRET or JWT_RS256_PUBLIC_KEY_FILE, or AUTH_DISABLED=true to run without authentication", "error", err)
 	}
-	_, err = db.Exec(string(sqlFile))
+	return []auth.Authenticator{jwt, auth.NewAPIKeyAuthenticator(keys)}
+}
+
+// rateLimitPolicies reads the limits of each route group from
+// RATE_LIMIT_READS and RATE_LIMIT_WRITES.
+func rateLimitPolicies() map[ratelimit.Group]ratelimit.Policy {
+	policies := map[ratelimit.Group]ratelimit.Policy{}
+	for _, setting := range []struct {
+		group    ratelimit.Group
+		variable string
+		fallback string
+	}{
+		{ratelimit.Reads, "RATE_LIMIT_READS", "300/1m"},
+		{ratelimit.Writes, "RATE_LIMIT_WRITES", "60/1m"},
+	} {
+		if policy, ok := rateLimitPolicy(setting.variable, setting.fallback); ok {
+			policies[setting.group] = policy
+		}
+	}
+	return policies
+}
+
+// rateLimitPolicy reads a limit written as limit/window, e.g. 300/1m, from
+// the environment variable, or uses fallback if it is unset. It reports
+// false for a limit set to off.
+func rateLimitPolicy(variable, fallback string) (ratelimit.Policy, bool) {
+	text := os.Getenv(variable)
+	if text == "" {
+		text = fallback
+	}
+	if text == "off" {
+		return ratelimit.Policy{}, false
+	}
+	policy, err := ratelimit.ParsePolicy(text)
+	if err != nil {
+		fatal("invalid rate limit", "variable", variable, "error", err)
+	}
+	return policy, true
+}
+
+// registerBusinessMetrics exposes gauges about the inventory, read from the
+// services on every scrape.
+func registerBusinessMetrics(registry *metrics.Registry, cars service.CarServiceInterface) {
+	registry.NewGaugeFunc("drivethrough_cars", "Cars for sale by fuel type.", []string{"fuel_type"}, func(ctx context.Context) ([]metrics.Value, error) {
+		counts, err := cars.CountCarsByFuelType(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]metrics.Value, 0, len(counts))
+		for fuelType, count := range counts {
+			values = append(values, metrics.Value{Labels: []string{fuelType}, Value: float64(count)})
+		}
+		return values, nil
+	})
+}
+
+// fatal logs an error that keeps the server from running and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// runMigrate implements the `migrate up|down [steps]|status` subcommands.
+func runMigrate(db *sql.DB, args []string) error {
+	migrator, err := migrations.New(db)
 	if err != nil {
 		return err
 	}
-	return nil
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number")
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			slog.Info("reverted migration", "version", migration.Version, "name", migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+}
+
+// unmatched answers requests no route matches with a problem of the given
+// status. The router only runs its middleware for the routes it matches,
+// so the middleware these requests need too is applied here.
+func unmatched(status int, middlewares ...mux.MiddlewareFunc) http.Handler {
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteProblem(w, r, status, "")
+	})
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
 }
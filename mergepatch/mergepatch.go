@@ -0,0 +1,76 @@
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Apply applies an RFC 7396 JSON merge patch to the original document:
+// objects are merged recursively, null removes a member and any other
+// value replaces the original one.
+func Apply(original, patch []byte) ([]byte, error) {
+	originalValue, err := decode(original)
+	if err != nil {
+		return nil, err
+	}
+	patchValue, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(originalValue, patchValue))
+}
+
+// IsObject reports whether the document is a JSON object, the only kind of
+// patch that makes sense for a resource.
+func IsObject(document []byte) bool {
+	value, err := decode(document)
+	if err != nil {
+		return false
+	}
+	_, ok := value.(map[string]any)
+	return ok
+}
+
+func merge(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = merge(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// decode keeps numbers as json.Number so integers survive the round-trip
+// without turning into floats.
+func decode(document []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// ApplyTo patches the JSON form of original and decodes the result into target.
+func ApplyTo(original any, patch []byte, target any) error {
+	document, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+	merged, err := Apply(document, patch)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(merged, target)
+}
@@ -0,0 +1,70 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		want     string
+	}{
+		{"replaces a member", `{"name": "Civic", "year": "2023"}`, `{"year": "2024"}`, `{"name": "Civic", "year": "2024"}`},
+		{"null removes a member", `{"name": "Civic", "vin": "1M8GDM9AXKP042788"}`, `{"vin": null}`, `{"name": "Civic"}`},
+		{"null for a missing member is a no-op", `{"name": "Civic"}`, `{"vin": null}`, `{"name": "Civic"}`},
+		{"merges nested objects", `{"engine": {"engine_id": "a", "displacement": 2000}}`, `{"engine": {"engine_id": "b"}}`, `{"engine": {"engine_id": "b", "displacement": 2000}}`},
+		{"removes nested members", `{"engine": {"engine_id": "a", "displacement": 2000}}`, `{"engine": {"displacement": null}}`, `{"engine": {"engine_id": "a"}}`},
+		{"creates nested objects", `{"name": "Civic"}`, `{"price": {"amount": "1.00"}}`, `{"name": "Civic", "price": {"amount": "1.00"}}`},
+		{"replaces an object with a scalar", `{"engine": {"engine_id": "a"}}`, `{"engine": 1}`, `{"engine": 1}`},
+		{"replaces arrays whole", `{"tags": ["a", "b"]}`, `{"tags": ["c"]}`, `{"tags": ["c"]}`},
+		{"a non-object patch replaces the document", `{"name": "Civic"}`, `["name"]`, `["name"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Apply([]byte(test.original), []byte(test.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalJSON(t, got, []byte(test.want)) {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyRejectsMalformedPatches(t *testing.T) {
+	if _, err := Apply([]byte(`{}`), []byte(`{"name": `)); err == nil {
+		t.Error("a malformed patch was applied")
+	}
+}
+
+func TestIsObject(t *testing.T) {
+	for document, want := range map[string]bool{
+		`{"name": "Civic"}`: true,
+		`{}`:                true,
+		`["name"]`:          false,
+		`"name"`:            false,
+		`null`:              false,
+		`{"name": `:         false,
+	} {
+		if got := IsObject([]byte(document)); got != want {
+			t.Errorf("IsObject(%s) = %v, want %v", document, got, want)
+		}
+	}
+}
+
+func equalJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RegisterDBStats exposes the connection pool statistics of db.
+func RegisterDBStats(r *Registry, db *sql.DB) {
+	stat := func(read func(sql.DBStats) float64) CollectFunc {
+		return func(context.Context) ([]Value, error) {
+			return []Value{{Value: read(db.Stats())}}, nil
+		}
+	}
+	r.NewGaugeFunc("drivethrough_db_open_connections", "Open database connections, in use or idle.", nil,
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc("drivethrough_db_in_use_connections", "Database connections in use.", nil,
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc("drivethrough_db_idle_connections", "Idle database connections.", nil,
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewGaugeFunc("drivethrough_db_max_open_connections", "Limit of open database connections, 0 for none.", nil,
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewCounterFunc("drivethrough_db_wait_count_total", "Times a query waited for a free database connection.", nil,
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc("drivethrough_db_wait_duration_seconds_total", "Time spent waiting for a free database connection.", nil,
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// HTTPMiddleware counts the requests served and times them, labeled by
+// method, the route template the router matched and the response status.
+// Methods other than the standard ones are labeled other, so clients
+// cannot create series at will, and requests no route matched are labeled
+// unmatched. A mux.Router skips its middleware for those, so its
+// NotFoundHandler and MethodNotAllowedHandler need wrapping as well.
+func HTTPMiddleware(r *Registry) mux.MiddlewareFunc {
+	requests := r.NewCounter("drivethrough_http_requests_total", "HTTP requests served.", "method", "route", "status")
+	durations := r.NewHistogram("drivethrough_http_request_duration_seconds", "Time taken to serve HTTP requests.", DefaultBuckets, "method", "route", "status")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, req)
+
+			route := "unmatched"
+			if current := mux.CurrentRoute(req); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			method := methodLabel(req.Method)
+			requests.Inc(method, route, strconv.Itoa(status))
+			durations.Observe(time.Since(start).Seconds(), method, route, strconv.Itoa(status))
+		})
+	}
+}
+
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "other"
+	}
+}
+
+// statusRecorder passes the response through while noting its status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestHTTPMiddlewareClampsMethods(t *testing.T) {
+	registry := NewRegistry()
+	router := mux.NewRouter()
+	router.Use(HTTPMiddleware(registry))
+	router.HandleFunc("/cars", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, method := range []string{"GET", "BREW", "X-RANDOM-1", "X-RANDOM-2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/cars", nil))
+	}
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`drivethrough_http_requests_total{method="GET",route="/cars",status="204"} 1`,
+		`drivethrough_http_requests_total{method="other",route="/cars",status="204"} 3`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "BREW") || strings.Contains(body, "X-RANDOM") {
+		t.Errorf("metrics contain a method label a client made up:\n%s", body)
+	}
+}
+
+func TestHTTPMiddlewareCountsUnmatched(t *testing.T) {
+	registry := NewRegistry()
+	middleware := HTTPMiddleware(registry)
+	router := mux.NewRouter()
+	router.Use(middleware)
+	router.NotFoundHandler = middleware(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	router.HandleFunc("/cars/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+
+	for _, target := range []string{"/cars/1", "/nope", "/nope/" + strings.Repeat("x", 40)} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/cars/1", nil))
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`drivethrough_http_requests_total{method="GET",route="/cars/{id}",status="200"} 1`,
+		`drivethrough_http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`drivethrough_http_requests_total{method="DELETE",route="unmatched",status="405"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/nope") {
+		t.Errorf("metrics contain a path no route matched:\n%s", body)
+	}
+}
@@ -0,0 +1,320 @@
+// Package metrics keeps counters, histograms and gauges and serves them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency
+// histograms: from a cached lookup to a slow export.
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics served by Handler.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// metric is one metric family: its name, help, type and samples.
+type metric interface {
+	describe() (name, help, kind string)
+	samples(ctx context.Context) ([]sample, error)
+}
+
+type sample struct {
+	// suffix is appended to the family name, e.g. _bucket for a histogram.
+	suffix string
+	labels []label
+	value  float64
+}
+
+type label struct {
+	name, value string
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Counter is a set of counters, one for each combination of label values.
+type Counter struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	series     map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter of the label values, given in the order the
+// labels were declared.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(delta float64, values ...string) {
+	key := seriesKey(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{values: slices.Clone(values)}
+		c.series[key] = s
+	}
+	s.value += delta
+}
+
+func (c *Counter) describe() (string, string, string) {
+	return c.name, c.help, "counter"
+}
+
+func (c *Counter) samples(context.Context) ([]sample, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var samples []sample
+	for _, s := range c.series {
+		samples = append(samples, sample{labels: labelPairs(c.labels, s.values), value: s.value})
+	}
+	return samples, nil
+}
+
+// Histogram is a set of histograms, one for each combination of label
+// values, counting observations into buckets by upper bound.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	// counts holds the observations of each bucket, not cumulated.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+	r.register(h)
+	return h
+}
+
+// Observe records a value in the histogram of the label values.
+func (h *Histogram) Observe(value float64, values ...string) {
+	key := seriesKey(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{values: slices.Clone(values), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *Histogram) describe() (string, string, string) {
+	return h.name, h.help, "histogram"
+}
+
+func (h *Histogram) samples(context.Context) ([]sample, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var samples []sample
+	for _, s := range h.series {
+		labels := labelPairs(h.labels, s.values)
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			samples = append(samples, sample{suffix: "_bucket", labels: append(slices.Clone(labels), label{"le", formatValue(bound)}), value: float64(cumulative)})
+		}
+		samples = append(samples,
+			sample{suffix: "_bucket", labels: append(slices.Clone(labels), label{"le", "+Inf"}), value: float64(s.count)},
+			sample{suffix: "_sum", labels: labels, value: s.sum},
+			sample{suffix: "_count", labels: labels, value: float64(s.count)},
+		)
+	}
+	return samples, nil
+}
+
+// Value is one reading of a metric collected by a CollectFunc.
+type Value struct {
+	// Labels are the label values, in the order the labels were declared.
+	Labels []string
+	Value  float64
+}
+
+// CollectFunc reads the current values of a metric when it is scraped.
+type CollectFunc func(ctx context.Context) ([]Value, error)
+
+// collected is a metric read on every scrape rather than kept.
+type collected struct {
+	name, help, kind string
+	labels           []string
+	collect          CollectFunc
+}
+
+// NewGaugeFunc registers a gauge read by collect on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect CollectFunc) {
+	r.register(&collected{name: name, help: help, kind: "gauge", labels: labels, collect: collect})
+}
+
+// NewCounterFunc registers a counter kept elsewhere, such as by
+// database/sql, and read by collect on every scrape.
+func (r *Registry) NewCounterFunc(name, help string, labels []string, collect CollectFunc) {
+	r.register(&collected{name: name, help: help, kind: "counter", labels: labels, collect: collect})
+}
+
+func (c *collected) describe() (string, string, string) {
+	return c.name, c.help, c.kind
+}
+
+func (c *collected) samples(ctx context.Context) ([]sample, error) {
+	values, err := c.collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]sample, 0, len(values))
+	for _, v := range values {
+		samples = append(samples, sample{labels: labelPairs(c.labels, v.Labels), value: v.Value})
+	}
+	return samples, nil
+}
+
+// Handler serves every metric of the registry. A metric that cannot be
+// collected is logged and left out, so the others are still served.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		metrics := slices.Clone(r.metrics)
+		r.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		out := bufio.NewWriter(w)
+		for _, m := range metrics {
+			name, help, kind := m.describe()
+			samples, err := m.samples(req.Context())
+			if err != nil {
+				slog.ErrorContext(req.Context(), "error while collecting metric", "metric", name, "error", err)
+				continue
+			}
+			sortSamples(samples)
+			fmt.Fprintf(out, "# HELP %s %s\n", name, escapeHelp(help))
+			fmt.Fprintf(out, "# TYPE %s %s\n", name, kind)
+			for _, s := range samples {
+				writeSample(out, name, s)
+			}
+		}
+		if err := out.Flush(); err != nil {
+			slog.ErrorContext(req.Context(), "error writing response", "error", err)
+		}
+	})
+}
+
+func writeSample(out *bufio.Writer, name string, s sample) {
+	out.WriteString(name)
+	out.WriteString(s.suffix)
+	if len(s.labels) > 0 {
+		out.WriteByte('{')
+		for i, l := range s.labels {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			fmt.Fprintf(out, `%s="%s"`, l.name, escapeLabel(l.value))
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(' ')
+	out.WriteString(formatValue(s.value))
+	out.WriteByte('\n')
+}
+
+// sortSamples orders the samples by label values so scrapes are stable.
+// The sort is stable so the buckets of a histogram stay in order.
+func sortSamples(samples []sample) {
+	slices.SortStableFunc(samples, func(a, b sample) int {
+		return strings.Compare(seriesKey(labelValues(a.labels, "le")), seriesKey(labelValues(b.labels, "le")))
+	})
+}
+
+func labelValues(labels []label, except string) []string {
+	values := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l.name != except {
+			values = append(values, l.value)
+		}
+	}
+	return values
+}
+
+func labelPairs(names, values []string) []label {
+	labels := make([]label, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			labels[i] = label{name, values[i]}
+		} else {
+			labels[i] = label{name: name}
+		}
+	}
+	return labels
+}
+
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
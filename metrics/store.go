@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// StoreMetrics times the calls made to a store and counts the ones that
+// failed.
+type StoreMetrics struct {
+	durations *Histogram
+	errors    *Counter
+}
+
+func NewStoreMetrics(r *Registry) *StoreMetrics {
+	return &StoreMetrics{
+		durations: r.NewHistogram("drivethrough_store_query_duration_seconds", "Time taken by store methods.", DefaultBuckets, "store", "method"),
+		errors:    r.NewCounter("drivethrough_store_query_errors_total", "Store method calls that failed.", "store", "method"),
+	}
+}
+
+// Observe records a call to a store method that started at start and
+// returned err. Outcomes the caller is told about, such as a missing row or
+// a failed validation, are not failures of the store and are not counted
+// as errors.
+func (m *StoreMetrics) Observe(store, method string, start time.Time, err error) {
+	m.durations.Observe(time.Since(start).Seconds(), store, method)
+	if err != nil && !expected(err) {
+		m.errors.Inc(store, method)
+	}
+}
+
+func expected(err error) bool {
+	for _, target := range []error{models.ErrNotFound, models.ErrValidation, models.ErrConflict, models.ErrInvalidID, models.ErrPreconditionFailed} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
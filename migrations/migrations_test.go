@@ -0,0 +1,105 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/internal/testdb"
+	"github.com/pranayyb/DriveThrough/migrations"
+)
+
+// legacySchema is the schema databases were set up with before migrations
+// existed, as the old store/schema.sql left it, with some rows.
+const legacySchema = `
+CREATE TABLE engines (
+    id UUID PRIMARY KEY,
+    displacement INT NOT NULL,
+    no_of_cylinders INT NOT NULL,
+    car_range INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE car (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    year VARCHAR(4) NOT NULL,
+    brand VARCHAR(255) NOT NULL,
+    fuel_type VARCHAR(50) NOT NULL,
+    engine_id UUID NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO engines (id, displacement, no_of_cylinders, car_range) VALUES
+    ('e1f86b1a-0873-4c19-bae2-fc60329d0140', 2000, 4, 600),
+    ('f4a9c66b-8e38-419b-93c4-215d5cefb318', 1600, 4, 550);
+
+INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price) VALUES
+    ('c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3', 'Honda Civic', '2023', 'Honda', 'Petrol', 'e1f86b1a-0873-4c19-bae2-fc60329d0140', 25000.00),
+    ('9d6a56f8-79c3-4931-a5c0-6b290c84ba2f', 'Toyota Corolla', '2022', 'Toyota', 'Petrol', 'f4a9c66b-8e38-419b-93c4-215d5cefb318', 22000.50);
+`
+
+func TestUpKeepsLegacyRows(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, legacySchema); err != nil {
+		t.Fatalf("creating legacy schema: %v", err)
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	var engines, cars int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM engine").Scan(&engines); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM car").Scan(&cars); err != nil {
+		t.Fatal(err)
+	}
+	if engines != 2 || cars != 2 {
+		t.Fatalf("after Up there are %d engines and %d cars, want 2 of each", engines, cars)
+	}
+
+	var name string
+	var displacement int
+	var priceMinor int64
+	err = db.QueryRowContext(ctx, `SELECT c.name, e.displacement, c.price_minor FROM car c JOIN engine e ON c.engine_id = e.id WHERE c.id = '9d6a56f8-79c3-4931-a5c0-6b290c84ba2f'`).
+		Scan(&name, &displacement, &priceMinor)
+	if err != nil {
+		t.Fatalf("reading migrated car: %v", err)
+	}
+	if name != "Toyota Corolla" || displacement != 1600 || priceMinor != 2200050 {
+		t.Fatalf("migrated car is %q with a %d engine for %d cents, want Toyota Corolla with a 1600 engine for 2200050 cents", name, displacement, priceMinor)
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price_minor, currency, price_base)
+		VALUES ('5e9df51a-8d7a-4d84-9c58-4ccfe5c7db06', 'Ghost', '2023', 'BMW', 'Petrol', '9746be12-07b7-42a3-b8ab-7d1f209b63d7', 1, 'USD', 1)`); err == nil {
+		t.Fatal("a car with an unknown engine was accepted, the foreign key was not added")
+	}
+}
+
+func TestUpIsRepeatable(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("first Up: %v", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("second Up applied %d migrations, want none", len(applied))
+	}
+}
@@ -0,0 +1,44 @@
+-- 0001 is the baseline: it creates the schema on an empty database and
+-- adopts the schema of a database set up before migrations existed,
+-- keeping its rows. Nothing here may drop or truncate.
+
+-- the old schema.sql named the engine table engines
+DO $$
+BEGIN
+    IF to_regclass('engines') IS NOT NULL AND to_regclass('engine') IS NULL THEN
+        ALTER TABLE engines RENAME TO engine;
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS engine (
+    id UUID PRIMARY KEY,
+    displacement INT NOT NULL,
+    no_of_cylinders INT NOT NULL,
+    car_range INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS car (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    year VARCHAR(4) NOT NULL,
+    brand VARCHAR(255) NOT NULL,
+    fuel_type VARCHAR(50) NOT NULL,
+    engine_id UUID NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- an existing car table may lack the foreign key, or have it pointing at
+-- the engines table under its old name, which the rename above follows
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_engine_id' AND conrelid = 'car'::regclass) THEN
+        ALTER TABLE car ADD CONSTRAINT fk_engine_id FOREIGN KEY (engine_id) REFERENCES engine(id);
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_car_brand ON car (brand);
+CREATE INDEX IF NOT EXISTS idx_car_engine_id ON car (engine_id);
@@ -0,0 +1,2 @@
+-- fails while detached cars exist; give them an engine or purge them first
+ALTER TABLE car ALTER COLUMN engine_id SET NOT NULL;
@@ -0,0 +1,2 @@
+-- a car may be detached from its engine when the engine is deleted
+ALTER TABLE car ALTER COLUMN engine_id DROP NOT NULL;
@@ -0,0 +1,15 @@
+-- prices in other currencies are converted back to US dollars
+ALTER TABLE car ADD COLUMN price DECIMAL(10, 2);
+
+UPDATE car SET price = price_base / 100.0;
+
+DROP INDEX IF EXISTS idx_car_price_base;
+
+ALTER TABLE car
+    ALTER COLUMN price SET NOT NULL,
+    DROP CONSTRAINT fk_currency,
+    DROP COLUMN price_minor,
+    DROP COLUMN currency,
+    DROP COLUMN price_base;
+
+DROP TABLE IF EXISTS exchange_rate;
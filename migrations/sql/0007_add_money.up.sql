@@ -0,0 +1,28 @@
+-- rate is how many units of the currency one US dollar, the base currency,
+-- buys; the base currency itself is always 1
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    currency CHAR(3) PRIMARY KEY,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO exchange_rate (currency, rate) VALUES ('USD', 1) ON CONFLICT DO NOTHING;
+
+-- prices become an amount in the minor unit of their currency; existing
+-- prices are US dollars. price_base is the price in cents of the base
+-- currency, which listings filter and sort on.
+ALTER TABLE car
+    ADD COLUMN price_minor BIGINT,
+    ADD COLUMN currency CHAR(3),
+    ADD COLUMN price_base BIGINT;
+
+UPDATE car SET price_minor = ROUND(price * 100), currency = 'USD', price_base = ROUND(price * 100);
+
+ALTER TABLE car
+    ALTER COLUMN price_minor SET NOT NULL,
+    ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN price_base SET NOT NULL,
+    DROP COLUMN price,
+    ADD CONSTRAINT fk_currency FOREIGN KEY (currency) REFERENCES exchange_rate(currency);
+
+CREATE INDEX IF NOT EXISTS idx_car_price_base ON car (price_base);
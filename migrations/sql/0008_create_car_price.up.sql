@@ -0,0 +1,17 @@
+-- one row per price a car has been listed at
+CREATE TABLE IF NOT EXISTS car_price (
+    id BIGSERIAL PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL REFERENCES exchange_rate(currency),
+    -- lower than the previous price of the car
+    dropped BOOLEAN NOT NULL DEFAULT FALSE,
+    changed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_price_car_id ON car_price (car_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_car_price_dropped ON car_price (changed_at) WHERE dropped;
+
+-- the history of existing cars starts with their current price
+INSERT INTO car_price (car_id, price_minor, currency, changed_at)
+SELECT id, price_minor, currency, COALESCE(created_at, CURRENT_TIMESTAMP) FROM car;
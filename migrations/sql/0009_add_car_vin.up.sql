@@ -0,0 +1,5 @@
+ALTER TABLE car ADD COLUMN IF NOT EXISTS vin CHAR(17);
+
+-- a vehicle can only be listed once at a time; cars in the trash keep
+-- their VIN but do not hold it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_car_vin ON car (vin) WHERE deleted_at IS NULL;
@@ -0,0 +1,10 @@
+-- keys of different principals may collide once the principal is gone, so
+-- only the most recent of each is kept
+DELETE FROM idempotency_key k
+USING idempotency_key newer
+WHERE k.scope = newer.scope AND k.key = newer.key
+    AND (k.created_at, k.principal) < (newer.created_at, newer.principal);
+
+ALTER TABLE idempotency_key DROP CONSTRAINT IF EXISTS idempotency_key_pkey;
+ALTER TABLE idempotency_key DROP COLUMN IF EXISTS principal;
+ALTER TABLE idempotency_key ADD CONSTRAINT idempotency_key_pkey PRIMARY KEY (scope, key);
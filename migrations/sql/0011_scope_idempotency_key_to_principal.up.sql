@@ -0,0 +1,7 @@
+-- keys are chosen by clients, so two clients may pick the same one; each
+-- principal gets keys of its own. Keys stored before this are kept under
+-- the empty principal and expire as usual.
+ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS principal VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE idempotency_key DROP CONSTRAINT IF EXISTS idempotency_key_pkey;
+ALTER TABLE idempotency_key ADD CONSTRAINT idempotency_key_pkey PRIMARY KEY (principal, scope, key);
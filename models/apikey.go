@@ -0,0 +1,72 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes an API key can be granted. Write scopes cover creating and
+// changing, delete scopes moving to the trash; neither includes reading.
+// Purging the trash is left to admins and no scope grants it.
+const (
+	ScopeCarsRead      = "cars:read"
+	ScopeCarsWrite     = "cars:write"
+	ScopeCarsDelete    = "cars:delete"
+	ScopeEnginesRead   = "engines:read"
+	ScopeEnginesWrite  = "engines:write"
+	ScopeEnginesDelete = "engines:delete"
+)
+
+var apiKeyScopes = []string{ScopeCarsRead, ScopeCarsWrite, ScopeCarsDelete, ScopeEnginesRead, ScopeEnginesWrite, ScopeEnginesDelete}
+
+// APIKey gives a partner machine access to the routes its scopes allow. Only
+// a hash of the key is kept; the key itself is shown once, when issued.
+type APIKey struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Prefix is the start of the key, enough to tell keys apart without
+	// revealing them.
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	Hash      string     `json:"-"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is optional; a key without it stays valid until revoked.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// IssuedAPIKey is the response to issuing a key, the only one that
+// includes the key.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+func ValidateAPIKeyRequest(req APIKeyRequest, now time.Time) error {
+	var errs ValidationErrors
+	if req.Name == "" {
+		errs.add("name", CodeRequired, "name is required")
+	}
+	if len(req.Scopes) == 0 {
+		errs.add("scopes", CodeRequired, "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			errs.add("scopes", CodeInvalidChoice, "scope "+scope+" is not one of: "+strings.Join(apiKeyScopes, ", "))
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		errs.add("expires_at", CodeOutOfRange, "expires_at must be in the future")
+	}
+	return errs.Err()
+}
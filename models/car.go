@@ -1,113 +1,141 @@
 package models
 
 import (
-	"errors"
 	"github.com/google/uuid"
 	"strconv"
 	"time"
 )
 
 type Car struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Year      string    `json:"year"`
-	Brand     string    `json:"brand"`
-	FuelType  string    `json:"fuel_type"`
-	Engine    Engine    `json:"engine"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Year     string    `json:"year"`
+	Brand    string    `json:"brand"`
+	FuelType string    `json:"fuel_type"`
+	// VIN is the vehicle identification number, empty when unknown. No two
+	// live cars share one.
+	VIN    string `json:"vin,omitempty"`
+	Engine Engine `json:"engine"`
+	Price  Money  `json:"price"`
+	// OriginalPrice is the stored price when Price was converted to
+	// another currency on the way out.
+	OriginalPrice *Money     `json:"original_price,omitempty"`
+	Version       int64      `json:"version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+	// BasePrice is Price converted to BaseCurrency at the current exchange
+	// rate, in minor units. Listings filter and sort on it so that prices
+	// in different currencies compare correctly. Stores keep it up to date
+	// when prices or rates change.
+	BasePrice int64 `json:"-"`
 }
 
 type CarRequest struct {
-	Name     string  `json:"name"`
-	Year     string  `json:"year"`
-	Brand    string  `json:"brand"`
-	FuelType string  `json:"fuel_type"`
-	Engine   Engine  `json:"engine"`
-	Price    float64 `json:"price"`
+	Name     string `json:"name"`
+	Year     string `json:"year"`
+	Brand    string `json:"brand"`
+	FuelType string `json:"fuel_type"`
+	VIN      string `json:"vin,omitempty"`
+	Engine   Engine `json:"engine"`
+	Price    Money  `json:"price"`
 }
 
 func ValidateRequest(carReq CarRequest) error {
-	if err := validateName(carReq.Name); err != nil {
-		return err
-	}
-	if err := validateYear(carReq.Year); err != nil {
-		return err
-	}
-	if err := validateBrand(carReq.Brand); err != nil {
-		return err
-	}
-	if err := validateFuelType(carReq.FuelType); err != nil {
-		return err
-	}
-	if err := validateEngine(carReq.Engine); err != nil {
-		return err
-	}
-	if err := validatePrice(carReq.Price); err != nil {
-		return err
-	}
-	return nil
+	var errs ValidationErrors
+	validateCar(&errs, carReq, true)
+	return errs.Err()
 }
-func validateName(name string) error {
+
+// ValidateCreateRequest validates a new car. Unlike updates, a new car may
+// leave out engine_id, in which case its engine fields describe a new
+// engine to create along with the car.
+func ValidateCreateRequest(carReq CarRequest) error {
+	var errs ValidationErrors
+	validateCar(&errs, carReq, false)
+	return errs.Err()
+}
+
+// ValidatePatchedRequest validates a car after a merge patch. A patch can
+// only point the car at another engine, so the engine's own fields are not
+// checked, and a car whose engine was detached may stay without one.
+func ValidatePatchedRequest(carReq CarRequest, detached bool) error {
+	var errs ValidationErrors
+	validateName(&errs, carReq.Name)
+	validateYear(&errs, carReq.Year)
+	validateBrand(&errs, carReq.Brand)
+	validateFuelType(&errs, carReq.FuelType)
+	if carReq.VIN != "" {
+		validateVIN(&errs, carReq.VIN)
+	}
+	if carReq.Engine.EngineID == uuid.Nil && !detached {
+		errs.add("engine.engine_id", CodeRequired, "engine id is required")
+	}
+	validatePrice(&errs, carReq.Price)
+	return errs.Err()
+}
+
+// HasInlineEngine reports whether the request describes a new engine
+// instead of referencing an existing one.
+func (c CarRequest) HasInlineEngine() bool {
+	return c.Engine.EngineID == uuid.Nil
+}
+
+func validateCar(errs *ValidationErrors, carReq CarRequest, requireEngineID bool) {
+	validateName(errs, carReq.Name)
+	validateYear(errs, carReq.Year)
+	validateBrand(errs, carReq.Brand)
+	validateFuelType(errs, carReq.FuelType)
+	if carReq.VIN != "" {
+		validateVIN(errs, carReq.VIN)
+	}
+	validateEngine(errs, carReq.Engine, requireEngineID)
+	validatePrice(errs, carReq.Price)
+}
+
+func validateName(errs *ValidationErrors, name string) {
 	if name == "" {
-		return errors.New("name is required")
+		errs.add("name", CodeRequired, "name is required")
 	}
-	return nil
 }
 
-func validateYear(year string) error {
+func validateYear(errs *ValidationErrors, year string) {
 	if year == "" {
-		return errors.New("year is required")
+		errs.add("year", CodeRequired, "year is required")
+		return
 	}
-	_, err := strconv.Atoi(year)
+	yearInt, err := strconv.Atoi(year)
 	if err != nil {
-		return errors.New("year must be a valid number")
+		errs.add("year", CodeInvalidFormat, "year must be a valid number")
+		return
 	}
 	currentYear := time.Now().Year()
-	yearInt, _ := strconv.Atoi(year)
 	if yearInt < 1886 || yearInt > currentYear {
-		return errors.New("year must be between 1886 and the current year")
+		errs.add("year", CodeOutOfRange, "year must be between 1886 and the current year")
 	}
-	return nil
 }
 
-func validateBrand(brand string) error {
+func validateBrand(errs *ValidationErrors, brand string) {
 	if brand == "" {
-		return errors.New("brand is required")
+		errs.add("brand", CodeRequired, "brand is required")
 	}
-	return nil
 }
 
-func validateFuelType(fuelType string) error {
+func validateFuelType(errs *ValidationErrors, fuelType string) {
 	validFuelTypes := []string{"Petrol", "Diesel", "Electric", "Hybrid"}
 	for _, validType := range validFuelTypes {
 		if fuelType == validType {
-			return nil
+			return
 		}
 	}
-	return errors.New("fuel type must be one of: Petrol, Diesel, Electric, Hybrid")
-}
-
-func validateEngine(engine Engine) error {
-	if engine.EngineID == uuid.Nil {
-		return errors.New("engine id is reuqired")
-	}
-	if engine.Displacement <= 0 {
-		return errors.New("displacement must be greater than 0")
-	}
-	if engine.NoOfCylinders <= 0 {
-		return errors.New("number of cylinders must be greater than 0")
-	}
-	if engine.CarRange <= 0 {
-		return errors.New("car range must be greater than 0")
-	}
-	return nil
+	errs.add("fuel_type", CodeInvalidChoice, "fuel type must be one of: Petrol, Diesel, Electric, Hybrid")
 }
 
-func validatePrice(price float64) error {
-	if price <= 0 {
-		return errors.New("price must be greater than 0")
+func validateEngine(errs *ValidationErrors, engine Engine, requireID bool) {
+	if requireID && engine.EngineID == uuid.Nil {
+		errs.add("engine.engine_id", CodeRequired, "engine id is required")
 	}
-	return nil
+	validateDisplacement(errs, "engine.", engine.Displacement)
+	validateNoOfCylinders(errs, "engine.", engine.NoOfCylinders)
+	validateCarRange(errs, "engine.", engine.CarRange)
 }
@@ -1,16 +1,18 @@
 package models
 
 import (
-	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Engine struct {
-	EngineID      uuid.UUID `json:"engine_id"`
-	Displacement  int64     `json:"displacement"`
-	NoOfCylinders int64     `json:"noOfCylinders"`
-	CarRange      int64     `json:"carRange"`
+	EngineID      uuid.UUID  `json:"engine_id"`
+	Displacement  int64      `json:"displacement"`
+	NoOfCylinders int64      `json:"noOfCylinders"`
+	CarRange      int64      `json:"carRange"`
+	Version       int64      `json:"version,omitempty"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
 }
 
 type EngineRequest struct {
@@ -20,35 +22,45 @@ type EngineRequest struct {
 }
 
 func ValidateEngineRequest(engine EngineRequest) error {
-	if err := validateDisplacement(engine.Displacement); err != nil {
-		return err
-	}
-	if err := validateNoOfCylinders(engine.NoOfCylinders); err != nil {
-		return err
-	}
-	if err := validateCarRange(engine.CarRange); err != nil {
-		return err
-	}
-	return nil
+	var errs ValidationErrors
+	validateDisplacement(&errs, "", engine.Displacement)
+	validateNoOfCylinders(&errs, "", engine.NoOfCylinders)
+	validateCarRange(&errs, "", engine.CarRange)
+	return errs.Err()
 }
 
-func validateDisplacement(displacement int64) error {
+// The engine validators take a path prefix so the same checks report
+// "displacement" for an engine request and "engine.displacement" for a car.
+func validateDisplacement(errs *ValidationErrors, prefix string, displacement int64) {
 	if displacement <= 0 {
-		return errors.New("displacement must be greater than 0")
+		errs.add(prefix+"displacement", CodeOutOfRange, "displacement must be greater than 0")
 	}
-	return nil
 }
 
-func validateNoOfCylinders(noOfCylinders int64) error {
+func validateNoOfCylinders(errs *ValidationErrors, prefix string, noOfCylinders int64) {
 	if noOfCylinders <= 0 {
-		return errors.New("number of cylinders must be greater than 0")
+		errs.add(prefix+"noOfCylinders", CodeOutOfRange, "number of cylinders must be greater than 0")
 	}
-	return nil
 }
 
-func validateCarRange(carRange int64) error {
+func validateCarRange(errs *ValidationErrors, prefix string, carRange int64) {
 	if carRange <= 0 {
-		return errors.New("car range must be greater than 0")
+		errs.add(prefix+"carRange", CodeOutOfRange, "car range must be greater than 0")
 	}
-	return nil
+}
+
+// How DeleteEngine treats the live cars still using the engine.
+const (
+	// DeleteRestrict refuses to delete an engine that is in use.
+	DeleteRestrict = "restrict"
+	// DeleteCascade moves the cars to the trash along with the engine.
+	DeleteCascade = "cascade"
+	// DeleteDetach keeps the cars but unlinks them from the engine.
+	DeleteDetach = "detach"
+)
+
+// EngineDependents lists the live cars that deleting an engine would affect.
+type EngineDependents struct {
+	EngineID uuid.UUID   `json:"engine_id"`
+	Cars     []uuid.UUID `json:"cars"`
 }
@@ -0,0 +1,256 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Sentinel errors identifying each failure class. The typed errors below
+// match them with errors.Is, so callers can branch on the class and still
+// get a descriptive message.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	ErrConflict   = errors.New("conflict")
+	ErrInvalidID  = errors.New("invalid id")
+
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+type NotFoundError struct {
+	Resource string
+	ID       string
+}
+
+func NewNotFoundError(resource, id string) *NotFoundError {
+	return &NotFoundError{Resource: resource, ID: id}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// FieldError describes one invalid field. Field is the JSON path of the
+// field, e.g. engine.displacement, and Code a stable machine-readable reason.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	CodeRequired      = "required"
+	CodeInvalidFormat = "invalid_format"
+	CodeOutOfRange    = "out_of_range"
+	CodeInvalidChoice = "invalid_choice"
+	CodeNotFound      = "not_found"
+	CodeDuplicate     = "duplicate"
+	CodeMismatch      = "mismatch"
+	CodeReadOnly      = "read_only"
+)
+
+// ValidationErrors lists every invalid field of a request, so clients can
+// report all of them in a single round-trip.
+type ValidationErrors []FieldError
+
+func NewValidationError(field, code, message string) ValidationErrors {
+	return ValidationErrors{{Field: field, Code: code, Message: message}}
+}
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+		if fieldErr.Field != "" {
+			messages[i] = fieldErr.Field + ": " + fieldErr.Message
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationErrors) add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Err returns nil when no field failed, so callers can return it directly.
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// FromDecodeError turns an error from decoding a client document into a
+// validation error, pointing at the offending field when JSON reports it.
+func FromDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return NewValidationError(typeErr.Field, CodeInvalidFormat, typeErr.Field+" must be "+jsonKind(typeErr.Type))
+	}
+	return NewValidationError("", CodeInvalidFormat, err.Error())
+}
+
+func jsonKind(t reflect.Type) string {
+	if t == reflect.TypeOf(uuid.UUID{}) {
+		return "a UUID string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+type ConflictError struct {
+	Message string
+}
+
+func NewConflictError(message string) *ConflictError {
+	return &ConflictError{Message: message}
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+type InvalidIDError struct {
+	Resource string
+	ID       string
+}
+
+func NewInvalidIDError(resource, id string) *InvalidIDError {
+	return &InvalidIDError{Resource: resource, ID: id}
+}
+
+func (e *InvalidIDError) Error() string {
+	return fmt.Sprintf("%q is not a valid %s id", e.ID, e.Resource)
+}
+
+func (e *InvalidIDError) Is(target error) bool {
+	return target == ErrInvalidID
+}
+
+// PreconditionFailedError is returned when a conditional write was made
+// against a version of the resource that is no longer current.
+type PreconditionFailedError struct {
+	Resource string
+	ID       string
+}
+
+func NewPreconditionFailedError(resource, id string) *PreconditionFailedError {
+	return &PreconditionFailedError{Resource: resource, ID: id}
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("%s %s has been modified since it was read", e.Resource, e.ID)
+}
+
+func (e *PreconditionFailedError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
+
+// DependentsError is returned when a resource cannot be deleted because
+// other resources still reference it.
+type DependentsError struct {
+	Resource   string
+	ID         string
+	Dependents []uuid.UUID
+}
+
+func NewDependentsError(resource, id string, dependents []uuid.UUID) *DependentsError {
+	return &DependentsError{Resource: resource, ID: id, Dependents: dependents}
+}
+
+func (e *DependentsError) Error() string {
+	return fmt.Sprintf("%s %s is still used by %d car(s)", e.Resource, e.ID, len(e.Dependents))
+}
+
+func (e *DependentsError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// DuplicateError is returned when a write would give a resource a value of
+// a field that must be unique and is already taken.
+type DuplicateError struct {
+	Resource string
+	Field    string
+	Value    string
+}
+
+func NewDuplicateError(resource, field, value string) *DuplicateError {
+	return &DuplicateError{Resource: resource, Field: field, Value: value}
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("a %s with %s %s already exists", e.Resource, e.Field, e.Value)
+}
+
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// UnauthenticatedError is returned when a request carries no credentials,
+// or credentials that cannot be trusted.
+type UnauthenticatedError struct {
+	Reason string
+}
+
+func NewUnauthenticatedError(reason string) *UnauthenticatedError {
+	return &UnauthenticatedError{Reason: reason}
+}
+
+func (e *UnauthenticatedError) Error() string {
+	return e.Reason
+}
+
+func (e *UnauthenticatedError) Is(target error) bool {
+	return target == ErrUnauthenticated
+}
+
+// ForbiddenError is returned when an authenticated caller is not allowed to
+// make a request.
+type ForbiddenError struct {
+	Message string
+}
+
+func NewForbiddenError(message string) *ForbiddenError {
+	return &ForbiddenError{Message: message}
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+const (
+	SortByPrice     = "price"
+	SortByYear      = "year"
+	SortByCreatedAt = "created_at"
+)
+
+const (
+	SortByDisplacement = "displacement"
+	SortByCylinders    = "cylinders"
+	SortByRange        = "range"
+)
+
+type CarFilter struct {
+	Brands    []string
+	FuelTypes []string
+	YearMin   int
+	YearMax   int
+	// PriceMin and PriceMax are in Currency, or in BaseCurrency if it is
+	// empty. Stores expect them in BaseCurrency; the service converts them.
+	PriceMin        *Money
+	PriceMax        *Money
+	DisplacementMin *int64
+	DisplacementMax *int64
+	CylindersMin    *int64
+	CylindersMax    *int64
+	// PriceDroppedSince keeps the cars whose price was lowered at or after
+	// the given time.
+	PriceDroppedSince *time.Time
+	SortBy            string
+	Descending        bool
+	Cursor            string
+	Limit             int
+	// Currency, when set, is the currency prices are converted to on the
+	// way out.
+	Currency string
+}
+
+type CarPage struct {
+	Cars       []Car  `json:"cars"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type EngineFilter struct {
+	DisplacementMin *int64
+	DisplacementMax *int64
+	CylindersMin    *int64
+	CylindersMax    *int64
+	RangeMin        *int64
+	RangeMax        *int64
+	SortBy          string
+	Descending      bool
+	Cursor          string
+	Limit           int
+}
+
+// EngineSummary is an engine as listed in the catalog, along with the
+// number of live cars using it.
+type EngineSummary struct {
+	Engine
+	CarCount int64 `json:"car_count"`
+}
+
+type EnginePage struct {
+	Engines    []EngineSummary `json:"engines"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Cursor marks the last row of a page for keyset pagination: the next page
+// starts right after (Value, ID) in the requested sort order. A cursor is
+// only valid for the sort column and direction it was issued for.
+type Cursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	Value      string    `json:"v"`
+	ID         uuid.UUID `json:"id"`
+}
+
+func ValidateCarFilter(filter *CarFilter) error {
+	var errs ValidationErrors
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = SortByCreatedAt
+	case SortByPrice, SortByYear, SortByCreatedAt:
+	default:
+		errs.add("sort", CodeInvalidChoice, "sort must be one of: price, year, created_at")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageSize {
+		errs.add("limit", CodeOutOfRange, "limit must be between 1 and "+strconv.Itoa(MaxPageSize))
+	}
+	if filter.YearMin != 0 && filter.YearMax != 0 && filter.YearMin > filter.YearMax {
+		errs.add("year_min", CodeOutOfRange, "year_min must not be greater than year_max")
+	}
+	if filter.Currency != "" && !ValidCurrency(filter.Currency) {
+		errs.add("currency", CodeInvalidChoice, "currency must be a supported ISO 4217 code such as USD or EUR")
+	}
+	if filter.PriceMin != nil && filter.PriceMax != nil && filter.PriceMin.Amount > filter.PriceMax.Amount {
+		errs.add("price_min", CodeOutOfRange, "price_min must not be greater than price_max")
+	}
+	if filter.DisplacementMin != nil && filter.DisplacementMax != nil && *filter.DisplacementMin > *filter.DisplacementMax {
+		errs.add("displacement_min", CodeOutOfRange, "displacement_min must not be greater than displacement_max")
+	}
+	if filter.CylindersMin != nil && filter.CylindersMax != nil && *filter.CylindersMin > *filter.CylindersMax {
+		errs.add("cylinders_min", CodeOutOfRange, "cylinders_min must not be greater than cylinders_max")
+	}
+	if filter.Cursor != "" {
+		cursor, err := DecodeCursor(filter.Cursor)
+		if err != nil {
+			errs.add("cursor", CodeInvalidFormat, "cursor is malformed")
+		} else if cursor.SortBy != filter.SortBy || cursor.Descending != filter.Descending {
+			errs.add("cursor", CodeInvalidChoice, "cursor was issued for a different sort order")
+		}
+	}
+	return errs.Err()
+}
+
+func ValidateEngineFilter(filter *EngineFilter) error {
+	var errs ValidationErrors
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = SortByDisplacement
+	case SortByDisplacement, SortByCylinders, SortByRange:
+	default:
+		errs.add("sort", CodeInvalidChoice, "sort must be one of: displacement, cylinders, range")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageSize {
+		errs.add("limit", CodeOutOfRange, "limit must be between 1 and "+strconv.Itoa(MaxPageSize))
+	}
+	if filter.DisplacementMin != nil && filter.DisplacementMax != nil && *filter.DisplacementMin > *filter.DisplacementMax {
+		errs.add("displacement_min", CodeOutOfRange, "displacement_min must not be greater than displacement_max")
+	}
+	if filter.CylindersMin != nil && filter.CylindersMax != nil && *filter.CylindersMin > *filter.CylindersMax {
+		errs.add("cylinders_min", CodeOutOfRange, "cylinders_min must not be greater than cylinders_max")
+	}
+	if filter.RangeMin != nil && filter.RangeMax != nil && *filter.RangeMin > *filter.RangeMax {
+		errs.add("range_min", CodeOutOfRange, "range_min must not be greater than range_max")
+	}
+	if filter.Cursor != "" {
+		cursor, err := DecodeCursor(filter.Cursor)
+		if err != nil {
+			errs.add("cursor", CodeInvalidFormat, "cursor is malformed")
+		} else if cursor.SortBy != filter.SortBy || cursor.Descending != filter.Descending {
+			errs.add("cursor", CodeInvalidChoice, "cursor was issued for a different sort order")
+		}
+	}
+	return errs.Err()
+}
+
+// EngineSortValue returns the engine's sort column in cursor form.
+func EngineSortValue(engine Engine, sortBy string) string {
+	switch sortBy {
+	case SortByCylinders:
+		return strconv.FormatInt(engine.NoOfCylinders, 10)
+	case SortByRange:
+		return strconv.FormatInt(engine.CarRange, 10)
+	default:
+		return strconv.FormatInt(engine.Displacement, 10)
+	}
+}
+
+// CarSortValue returns the value of the car's sort column in the textual
+// form used inside cursors.
+func CarSortValue(car Car, sortBy string) string {
+	switch sortBy {
+	case SortByPrice:
+		return strconv.FormatInt(car.BasePrice, 10)
+	case SortByYear:
+		return car.Year
+	default:
+		return car.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+func EncodeCursor(cursor Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(encoded string) (Cursor, error) {
+	var cursor Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, NewValidationError("cursor", CodeInvalidFormat, "cursor is malformed")
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, NewValidationError("cursor", CodeInvalidFormat, "cursor is malformed")
+	}
+	return cursor, nil
+}
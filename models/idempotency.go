@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// IdempotencyKeyTTL is how long a key replays its response. After that the
+// key may be reused for a new request.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyLease is how long the first request made with a key holds it
+// without completing. A request still unfinished after that is taken to
+// have died with its process, and a retry may take the key over.
+const IdempotencyLease = 5 * time.Minute
+
+// IdempotencyRecord is the first request made with an idempotency key and,
+// once it has completed, the response to replay to its retries.
+type IdempotencyRecord struct {
+	// Principal is the caller that made the request. Keys are chosen by
+	// clients, so each caller has keys of its own.
+	Principal   string
+	Scope       string
+	Key         string
+	RequestHash string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// Completed reports whether the response has been recorded, as opposed to
+// the first request still being processed.
+func (r IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
+
+// Expired reports whether a new request may take the key over: its response
+// is older than IdempotencyKeyTTL, or its first request has held it for
+// longer than IdempotencyLease without completing.
+func (r IdempotencyRecord) Expired(now time.Time) bool {
+	if !r.Completed() {
+		return r.CreatedAt.Before(now.Add(-IdempotencyLease))
+	}
+	return r.CreatedAt.Before(now.Add(-IdempotencyKeyTTL))
+}
@@ -0,0 +1,36 @@
+package models
+
+import "github.com/google/uuid"
+
+const (
+	// ImportModeAtomic creates every row or none of them.
+	ImportModeAtomic = "atomic"
+	// ImportModeBestEffort creates the valid rows and reports the others.
+	ImportModeBestEffort = "best_effort"
+)
+
+// MaxImportRows bounds the number of cars a single import may create.
+const MaxImportRows = 5000
+
+// ImportRow is one car read from an import file. Err holds the errors found
+// while decoding the row, in which case Car is incomplete.
+type ImportRow struct {
+	Line int
+	Car  CarRequest
+	Err  error
+}
+
+type ImportRowResult struct {
+	Line   int          `json:"line"`
+	ID     *uuid.UUID   `json:"id,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// ImportReport tells the outcome of every row of an import, in file order.
+type ImportReport struct {
+	Mode    string            `json:"mode"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BaseCurrency is the currency exchange rates are quoted against. Prices in
+// different currencies are compared in it when filtering and sorting.
+const BaseCurrency = "USD"
+
+// currencyExponents lists the supported ISO 4217 currencies with the number
+// of decimal places of their minor unit.
+var currencyExponents = map[string]int{
+	"AED": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2,
+	"DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "INR": 2, "JPY": 0, "KRW": 0, "KWD": 3,
+	"MXN": 2, "NOK": 2, "NZD": 2, "PLN": 2, "SEK": 2, "SGD": 2, "TRY": 2, "USD": 2,
+	"ZAR": 2,
+}
+
+// ValidCurrency reports whether code is a supported ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	_, ok := currencyExponents[code]
+	return ok
+}
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Money is an exact amount of a currency, counted in its minor unit, e.g.
+// cents for USD. In JSON the amount is a decimal string in the major unit,
+// {"amount": "24999.99", "currency": "USD"}, so it never passes through a
+// float.
+type Money struct {
+	Amount   int64
+	Currency string
+	// invalid keeps an amount that could not be parsed while decoding, so
+	// validation can report it along with the other fields.
+	invalid string
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// ParseMoney parses a decimal amount in the major unit of the currency. The
+// amount may not have more decimal places than the currency's minor unit.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, errors.New("unsupported currency " + currency)
+	}
+	if !decimalPattern.MatchString(amount) {
+		return Money{}, errors.New("amount must be a decimal number")
+	}
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if len(fraction) > exponent {
+		return Money{}, errors.New("amount has more decimal places than " + currency + " allows")
+	}
+	minor, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10)
+	if !ok || !minor.IsInt64() {
+		return Money{}, errors.New("amount is too large")
+	}
+	return Money{Amount: minor.Int64(), Currency: currency}, nil
+}
+
+// Decimal returns the amount in the major unit, with exactly as many
+// decimal places as the currency's minor unit.
+func (m Money) Decimal() string {
+	exponent := currencyExponents[m.Currency]
+	digits := new(big.Int).Abs(big.NewInt(m.Amount)).String()
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	if exponent == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(raw.Amount, raw.Currency)
+	if err != nil {
+		*m = Money{Currency: raw.Currency, invalid: raw.Amount}
+		return nil
+	}
+	*m = parsed
+	return nil
+}
+
+// Convert returns the amount in another currency given the exchange rates
+// of both currencies, rounded half away from zero to the target's minor
+// unit.
+func (m Money) Convert(currency string, fromRate, toRate *big.Rat) Money {
+	value := new(big.Rat).SetInt64(m.Amount)
+	value.Mul(value, toRate)
+	value.Quo(value, fromRate)
+	value.Mul(value, pow10(currencyExponents[currency]-currencyExponents[m.Currency]))
+	return Money{Amount: roundHalfAway(value), Currency: currency}
+}
+
+// BaseAmount returns the amount in minor units of BaseCurrency, given the
+// exchange rate of the amount's currency.
+func (m Money) BaseAmount(rate *big.Rat) int64 {
+	return m.Convert(BaseCurrency, rate, big.NewRat(1, 1)).Amount
+}
+
+func pow10(exponent int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponent))), nil)
+	if exponent < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), scale)
+	}
+	return new(big.Rat).SetInt(scale)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func roundHalfAway(value *big.Rat) int64 {
+	num := new(big.Int).Abs(value.Num())
+	quotient, remainder := new(big.Int).QuoRem(num, value.Denom(), new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(value.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	return quotient.Int64()
+}
+
+func validatePrice(errs *ValidationErrors, price Money) {
+	if price.Currency == "" {
+		errs.add("price.currency", CodeRequired, "currency is required")
+		return
+	}
+	if !ValidCurrency(price.Currency) {
+		errs.add("price.currency", CodeInvalidChoice, "currency must be a supported ISO 4217 code such as USD or EUR")
+		return
+	}
+	if price.invalid != "" {
+		if _, err := ParseMoney(price.invalid, price.Currency); err != nil {
+			errs.add("price.amount", CodeInvalidFormat, err.Error())
+		}
+		return
+	}
+	if price.Amount <= 0 {
+		errs.add("price.amount", CodeOutOfRange, "price must be greater than 0")
+	}
+}
+
+// maxRateScale is the number of decimal places kept for exchange rates.
+const maxRateScale = 10
+
+// ExchangeRate is the number of units of Currency that one unit of
+// BaseCurrency buys. The rate of BaseCurrency itself is always 1.
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExchangeRateRequest struct {
+	Rate string `json:"rate"`
+}
+
+// Rat returns the rate as an exact fraction. The rate must have been
+// validated or read from a store.
+func (r ExchangeRate) Rat() *big.Rat {
+	rate, _ := new(big.Rat).SetString(r.Rate)
+	return rate
+}
+
+// FormatRate renders a rate as a decimal without trailing zeros.
+func FormatRate(rate *big.Rat) string {
+	text := rate.FloatString(maxRateScale)
+	text = strings.TrimRight(text, "0")
+	return strings.TrimSuffix(text, ".")
+}
+
+func ValidateExchangeRate(currency string, req ExchangeRateRequest) error {
+	var errs ValidationErrors
+	switch {
+	case !ValidCurrency(currency):
+		errs.add("currency", CodeInvalidChoice, "currency must be a supported ISO 4217 code such as USD or EUR")
+	case currency == BaseCurrency:
+		errs.add("currency", CodeInvalidChoice, "the rate of the base currency "+BaseCurrency+" is always 1")
+	}
+	if req.Rate == "" {
+		errs.add("rate", CodeRequired, "rate is required")
+		return errs.Err()
+	}
+	whole, fraction, _ := strings.Cut(req.Rate, ".")
+	rate, ok := new(big.Rat).SetString(req.Rate)
+	switch {
+	case !ok || !decimalPattern.MatchString(req.Rate):
+		errs.add("rate", CodeInvalidFormat, "rate must be a decimal number")
+	case rate.Sign() <= 0:
+		errs.add("rate", CodeOutOfRange, "rate must be greater than 0")
+	case len(fraction) > maxRateScale || len(strings.TrimLeft(whole, "0")) > 10:
+		errs.add("rate", CodeOutOfRange, "rate must have at most 10 digits before and 10 after the decimal point")
+	}
+	return errs.Err()
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		ok       bool
+	}{
+		{"24999.99", "USD", 2499999, true},
+		{"25000", "USD", 2500000, true},
+		{"0.5", "USD", 50, true},
+		{"-1.25", "EUR", -125, true},
+		{"151", "JPY", 151, true},
+		{"1.234", "KWD", 1234, true},
+		{"1.5", "JPY", 0, false},
+		{"1.234", "USD", 0, false},
+		{"1e3", "USD", 0, false},
+		{"12,50", "EUR", 0, false},
+		{"", "USD", 0, false},
+		{".5", "USD", 0, false},
+		{"99999999999999999999", "USD", 0, false},
+		{"10", "XXX", 0, false},
+	}
+	for _, test := range tests {
+		money, err := ParseMoney(test.amount, test.currency)
+		if (err == nil) != test.ok {
+			t.Errorf("ParseMoney(%q, %s) returned error %v, want ok=%v", test.amount, test.currency, err, test.ok)
+			continue
+		}
+		if test.ok && money != (Money{Amount: test.want, Currency: test.currency}) {
+			t.Errorf("ParseMoney(%q, %s) = %d, want %d", test.amount, test.currency, money.Amount, test.want)
+		}
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Amount: 2499999, Currency: "USD"}, "24999.99"},
+		{Money{Amount: 5, Currency: "USD"}, "0.05"},
+		{Money{Amount: 0, Currency: "EUR"}, "0.00"},
+		{Money{Amount: -125, Currency: "EUR"}, "-1.25"},
+		{Money{Amount: 151, Currency: "JPY"}, "151"},
+		{Money{Amount: 1234, Currency: "KWD"}, "1.234"},
+	}
+	for _, test := range tests {
+		if got := test.money.Decimal(); got != test.want {
+			t.Errorf("%d %s renders as %q, want %q", test.money.Amount, test.money.Currency, got, test.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	body, err := json.Marshal(Money{Amount: 2499999, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"amount":"24999.99","currency":"USD"}` {
+		t.Fatalf("got %s", body)
+	}
+	var money Money
+	if err := json.Unmarshal(body, &money); err != nil {
+		t.Fatal(err)
+	}
+	if money != (Money{Amount: 2499999, Currency: "USD"}) {
+		t.Fatalf("round trip gave %+v", money)
+	}
+}
+
+func TestValidatePrice(t *testing.T) {
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{"amount": "10.00", "currency": "USD"}`, ""},
+		{`{"amount": "10.001", "currency": "USD"}`, CodeInvalidFormat},
+		{`{"amount": "ten", "currency": "USD"}`, CodeInvalidFormat},
+		{`{"amount": "0", "currency": "USD"}`, CodeOutOfRange},
+		{`{"amount": "10.00", "currency": "XXX"}`, CodeInvalidChoice},
+		{`{"amount": "10.00"}`, CodeRequired},
+	}
+	for _, test := range tests {
+		var price Money
+		if err := json.Unmarshal([]byte(test.body), &price); err != nil {
+			t.Fatalf("decoding %s: %v", test.body, err)
+		}
+		var errs ValidationErrors
+		validatePrice(&errs, price)
+		var code string
+		if len(errs) > 0 {
+			code = errs[0].Code
+		}
+		if code != test.code {
+			t.Errorf("%s got %v, want code %q", test.body, errs, test.code)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rat := func(s string) *big.Rat {
+		r, _ := new(big.Rat).SetString(s)
+		return r
+	}
+	tests := []struct {
+		name     string
+		money    Money
+		currency string
+		from, to string
+		want     int64
+	}{
+		{"to the base currency", Money{Amount: 10000, Currency: "EUR"}, "USD", "0.92", "1", 10870},
+		{"from the base currency", Money{Amount: 10000, Currency: "USD"}, "EUR", "1", "0.92", 9200},
+		{"to a currency without minor unit", Money{Amount: 10000, Currency: "USD"}, "JPY", "1", "151.4", 15140},
+		{"from a currency without minor unit", Money{Amount: 15140, Currency: "JPY"}, "USD", "151.4", "1", 10000},
+		{"to three decimals", Money{Amount: 10000, Currency: "USD"}, "KWD", "1", "0.3075", 30750},
+		{"half rounds up", Money{Amount: 1, Currency: "USD"}, "EUR", "1", "0.5", 1},
+		{"half rounds away from zero", Money{Amount: -1, Currency: "USD"}, "EUR", "1", "0.5", -1},
+		{"below half rounds down", Money{Amount: 1, Currency: "USD"}, "EUR", "1", "0.49", 0},
+		{"between non-base currencies", Money{Amount: 10000, Currency: "EUR"}, "GBP", "0.92", "0.79", 8587},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.money.Convert(test.currency, rat(test.from), rat(test.to))
+			if got != (Money{Amount: test.want, Currency: test.currency}) {
+				t.Errorf("got %v, want %d %s", got, test.want, test.currency)
+			}
+		})
+	}
+}
+
+func TestBaseAmount(t *testing.T) {
+	if got := (Money{Amount: 3200000, Currency: "EUR"}).BaseAmount(big.NewRat(92, 100)); got != 3478261 {
+		t.Errorf("32000.00 EUR at 0.92 is %d cents, want 3478261", got)
+	}
+	if got := (Money{Amount: 2500000, Currency: "USD"}).BaseAmount(big.NewRat(1, 1)); got != 2500000 {
+		t.Errorf("a base price changed to %d", got)
+	}
+}
+
+func TestValidateExchangeRate(t *testing.T) {
+	tests := []struct {
+		currency string
+		rate     string
+		field    string
+	}{
+		{"EUR", "0.92", ""},
+		{"JPY", "151.4", ""},
+		{"USD", "1", "currency"},
+		{"XXX", "1", "currency"},
+		{"EUR", "", "rate"},
+		{"EUR", "-1", "rate"},
+		{"EUR", "0", "rate"},
+		{"EUR", "1/2", "rate"},
+		{"EUR", "0.12345678901", "rate"},
+		{"EUR", "12345678901", "rate"},
+	}
+	for _, test := range tests {
+		err := ValidateExchangeRate(test.currency, ExchangeRateRequest{Rate: test.rate})
+		var errs ValidationErrors
+		errors.As(err, &errs)
+		var field string
+		if len(errs) > 0 {
+			field = errs[0].Field
+		}
+		if field != test.field {
+			t.Errorf("rate %q for %s got %v, want an error on %q", test.rate, test.currency, err, test.field)
+		}
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	for rate, want := range map[string]string{"0.9200": "0.92", "151.4": "151.4", "1": "1", "1/3": "0.3333333333"} {
+		r, _ := new(big.Rat).SetString(rate)
+		if got := FormatRate(r); got != want {
+			t.Errorf("FormatRate(%s) = %q, want %q", rate, got, want)
+		}
+	}
+}
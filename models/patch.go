@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// CarUpdate holds the columns changed by a partial update; nil fields are
+// left untouched by the store.
+type CarUpdate struct {
+	Name     *string
+	Year     *string
+	Brand    *string
+	FuelType *string
+	VIN      *string
+	EngineID *uuid.UUID
+	Price    *Money
+}
+
+type EngineUpdate struct {
+	Displacement  *int64
+	NoOfCylinders *int64
+	CarRange      *int64
+}
+
+// ValidateCarPatch rejects the members of a car patch that cannot be
+// applied. The nested engine may only be pointed at another engine by its
+// engine_id; its displacement, cylinders and range belong to the engine
+// and are changed by patching the engine itself.
+func ValidateCarPatch(patch []byte) error {
+	var members struct {
+		Engine json.RawMessage `json:"engine"`
+	}
+	if err := json.Unmarshal(patch, &members); err != nil {
+		return FromDecodeError(err)
+	}
+	var engine map[string]json.RawMessage
+	if json.Unmarshal(members.Engine, &engine) != nil {
+		// engine is absent, null, or not an object, which decoding the
+		// patched car reports
+		return nil
+	}
+	fields := make([]string, 0, len(engine))
+	for field := range engine {
+		if field != "engine_id" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	var errs ValidationErrors
+	for _, field := range fields {
+		errs.add("engine."+field, CodeReadOnly, "only engine.engine_id can be patched through a car, patch the engine to change its "+field)
+	}
+	return errs.Err()
+}
+
+func CarRequestFromCar(car Car) CarRequest {
+	return CarRequest{
+		Name:     car.Name,
+		Year:     car.Year,
+		Brand:    car.Brand,
+		FuelType: car.FuelType,
+		VIN:      car.VIN,
+		Engine:   car.Engine,
+		Price:    car.Price,
+	}
+}
+
+func EngineRequestFromEngine(engine Engine) EngineRequest {
+	return EngineRequest{
+		Displacement:  engine.Displacement,
+		NoOfCylinders: engine.NoOfCylinders,
+		CarRange:      engine.CarRange,
+	}
+}
+
+// NewCarUpdate returns the fields that differ between two versions of a car.
+func NewCarUpdate(before, after CarRequest) CarUpdate {
+	var update CarUpdate
+	if before.Name != after.Name {
+		update.Name = &after.Name
+	}
+	if before.Year != after.Year {
+		update.Year = &after.Year
+	}
+	if before.Brand != after.Brand {
+		update.Brand = &after.Brand
+	}
+	if before.FuelType != after.FuelType {
+		update.FuelType = &after.FuelType
+	}
+	if before.VIN != after.VIN {
+		update.VIN = &after.VIN
+	}
+	if before.Engine.EngineID != after.Engine.EngineID {
+		update.EngineID = &after.Engine.EngineID
+	}
+	if before.Price != after.Price {
+		update.Price = &after.Price
+	}
+	return update
+}
+
+func (u CarUpdate) IsEmpty() bool {
+	return u == CarUpdate{}
+}
+
+// NewEngineUpdate returns the fields that differ between two versions of an engine.
+func NewEngineUpdate(before, after EngineRequest) EngineUpdate {
+	var update EngineUpdate
+	if before.Displacement != after.Displacement {
+		update.Displacement = &after.Displacement
+	}
+	if before.NoOfCylinders != after.NoOfCylinders {
+		update.NoOfCylinders = &after.NoOfCylinders
+	}
+	if before.CarRange != after.CarRange {
+		update.CarRange = &after.CarRange
+	}
+	return update
+}
+
+func (u EngineUpdate) IsEmpty() bool {
+	return u == EngineUpdate{}
+}
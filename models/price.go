@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PricePoint is a price a car was listed at, from ChangedAt until the next
+// point. The first point of a car is its price when it was created.
+type PricePoint struct {
+	Price Money `json:"price"`
+	// OriginalPrice is the recorded price when Price was converted to
+	// another currency on the way out.
+	OriginalPrice *Money `json:"original_price,omitempty"`
+	// Dropped tells whether the price is lower than the one before it,
+	// compared in the base currency when the change was made.
+	Dropped   bool      `json:"dropped"`
+	ChangedAt time.Time `json:"changed_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DefaultTrashRetention is how long deleted cars and engines stay
+// restorable before a purge may remove them for good.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+type PurgeResult struct {
+	Purged        int64     `json:"purged"`
+	DeletedBefore time.Time `json:"deleted_before"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"strings"
+)
+
+// VINLength is the length of an ISO 3779 vehicle identification number.
+const VINLength = 17
+
+// vinWeights are the position weights of the check digit calculation.
+var vinWeights = [VINLength]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// vinValue maps a VIN character to the number it counts as in the check
+// digit calculation. I, O and Q are not allowed in a VIN, so they are
+// missing here.
+func vinValue(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'H':
+		return int(c-'A') + 1, true
+	case c >= 'J' && c <= 'N':
+		return int(c-'J') + 1, true
+	case c == 'P':
+		return 7, true
+	case c == 'R':
+		return 9, true
+	case c >= 'S' && c <= 'Z':
+		return int(c-'S') + 2, true
+	}
+	return 0, false
+}
+
+// NormalizeVIN trims a VIN and upper-cases it, the form it is stored in.
+func NormalizeVIN(vin string) string {
+	return strings.ToUpper(strings.TrimSpace(vin))
+}
+
+// VINCheckDigit computes the check digit of a VIN, the character expected
+// at its ninth position. The VIN must be 17 valid characters.
+func VINCheckDigit(vin string) byte {
+	sum := 0
+	for i := 0; i < VINLength; i++ {
+		value, _ := vinValue(vin[i])
+		sum += value * vinWeights[i]
+	}
+	if sum%11 == 10 {
+		return 'X'
+	}
+	return byte('0' + sum%11)
+}
+
+// ValidateVIN checks a normalized VIN: 17 characters out of digits and
+// capital letters other than I, O and Q, with a matching check digit.
+func ValidateVIN(vin string) error {
+	var errs ValidationErrors
+	validateVIN(&errs, vin)
+	return errs.Err()
+}
+
+func validateVIN(errs *ValidationErrors, vin string) {
+	if len(vin) != VINLength {
+		errs.add("vin", CodeInvalidFormat, "vin must be 17 characters long")
+		return
+	}
+	for i := 0; i < VINLength; i++ {
+		if _, ok := vinValue(vin[i]); !ok {
+			errs.add("vin", CodeInvalidFormat, "vin may only contain digits and the letters A to Z except I, O and Q")
+			return
+		}
+	}
+	if vin[8] != VINCheckDigit(vin) {
+		errs.add("vin", CodeInvalidFormat, "vin check digit does not match, the vin was probably mistyped")
+	}
+}
+
+// DecodedVIN is what a VIN tells about a vehicle without looking it up
+// anywhere.
+type DecodedVIN struct {
+	VIN string `json:"vin"`
+	// WMI is the world manufacturer identifier, the first three characters.
+	WMI string `json:"wmi"`
+	// VDS is the vehicle descriptor section, chosen by the manufacturer and
+	// ending with the check digit.
+	VDS string `json:"vds"`
+	// VIS is the vehicle identifier section, starting with the model year
+	// code and ending with the serial number.
+	VIS          string `json:"vis"`
+	Region       string `json:"region"`
+	Country      string `json:"country,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	// Brand is the manufacturer's main brand under the WMI, and Brands all
+	// the brands it sells under it, Brand first.
+	Brand     string   `json:"brand,omitempty"`
+	Brands    []string `json:"brands,omitempty"`
+	ModelYear int      `json:"model_year,omitempty"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVINCheckDigit(t *testing.T) {
+	for vin, want := range map[string]byte{
+		"1M8GDM9AXKP042788": 'X',
+		"1HGCM82633A004352": '3',
+		"11111111111111111": '1',
+		"JN1AZ4EH7DM430111": '7',
+		"WBA5R1C04PFN12345": '4',
+	} {
+		if got := VINCheckDigit(vin); got != want {
+			t.Errorf("VINCheckDigit(%s) = %c, want %c", vin, got, want)
+		}
+	}
+}
+
+func TestValidateVIN(t *testing.T) {
+	tests := []struct {
+		name string
+		vin  string
+		ok   bool
+	}{
+		{"check digit X", "1M8GDM9AXKP042788", true},
+		{"check digit 3", "1HGCM82633A004352", true},
+		{"all ones", "11111111111111111", true},
+		{"wrong check digit", "1M8GDM9A1KP042788", false},
+		{"mistyped character", "1M8GDM9AXKP042789", false},
+		{"contains I", "1M8GDM9AXKP0427I8", false},
+		{"contains O", "1HGCM82633A0O4352", false},
+		{"contains Q", "1HGCM82633A00435Q", false},
+		{"lower case", "1hgcm82633a004352", false},
+		{"punctuation", "1HGCM82633A-04352", false},
+		{"16 characters", "1HGCM82633A00435", false},
+		{"18 characters", "1HGCM82633A0043520", false},
+		{"empty", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateVIN(test.vin)
+			if (err == nil) != test.ok {
+				t.Fatalf("ValidateVIN(%q) = %v, want ok=%v", test.vin, err, test.ok)
+			}
+			var errs ValidationErrors
+			if err != nil && (!errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "vin" || errs[0].Code != CodeInvalidFormat) {
+				t.Errorf("got %v, want one invalid_format error on vin", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeVIN(t *testing.T) {
+	if got := NormalizeVIN("  1hgcm82633a004352\n"); got != "1HGCM82633A004352" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryLimiter drops the buckets of idle
+// clients.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will be full again if left alone, after
+	// which it is no different from a new one.
+	full time.Time
+}
+
+// MemoryLimiter keeps the buckets in the process, so each replica enforces
+// its own limit.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, policy Policy) (Decision, error) {
+	if policy.Limit <= 0 || policy.Window <= 0 {
+		return Decision{}, ErrInvalidPolicy
+	}
+	return l.take(key, policy, time.Now()), nil
+}
+
+// take takes a token from the bucket of key at the given time, refilling
+// it for the time since it was last used.
+func (l *MemoryLimiter) take(key string, policy Policy, now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	limit := float64(policy.Limit)
+	// tokens per nanosecond
+	rate := limit / float64(policy.Window)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(limit, b.tokens+float64(now.Sub(b.updated))*rate)
+	b.updated = now
+
+	decision := Decision{Limit: policy.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration((1 - b.tokens) / rate)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = time.Duration((limit - b.tokens) / rate)
+	b.full = now.Add(decision.Reset)
+	return decision
+}
+
+// sweep drops the buckets that have filled up again.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,170 @@
+// Package ratelimit throttles clients with token buckets, separately for
+// reads and writes, so one client cannot exhaust the database for the
+// others.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/auth"
+	"github.com/pranayyb/DriveThrough/handler"
+)
+
+// Policy allows Limit requests per Window. Tokens are refilled evenly over
+// the window, and a client that was idle can burst up to Limit at once.
+type Policy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParsePolicy reads a policy written as limit/window, e.g. 300/1m.
+func ParsePolicy(text string) (Policy, error) {
+	limitText, windowText, ok := strings.Cut(text, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q must be written as limit/window, e.g. 300/1m", text)
+	}
+	limit, err := strconv.Atoi(limitText)
+	if err != nil || limit <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q must allow a positive whole number of requests", text)
+	}
+	window, err := time.ParseDuration(windowText)
+	if err != nil || window <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q must have a positive window such as 1m", text)
+	}
+	return Policy{Limit: limit, Window: window}, nil
+}
+
+// ErrInvalidPolicy is returned for a policy that allows nothing.
+var ErrInvalidPolicy = errors.New("a rate limit policy needs a positive limit and window")
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token, when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter keeps the buckets. MemoryLimiter keeps them in the process; a
+// shared backend lets several replicas enforce one limit.
+type Limiter interface {
+	// Allow takes a token from the bucket of key, which holds at most
+	// policy.Limit tokens.
+	Allow(ctx context.Context, key string, policy Policy) (Decision, error)
+}
+
+// Group is a set of routes sharing a limit.
+type Group string
+
+const (
+	Reads  Group = "read"
+	Writes Group = "write"
+)
+
+// GroupOf puts reads in the Reads group and everything else in Writes.
+func GroupOf(r *http.Request) Group {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return Reads
+	default:
+		return Writes
+	}
+}
+
+// Middleware limits each client per route group and reports its budget in
+// the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers. A client out of tokens gets 429 with
+// Retry-After. Groups without a policy are not limited. If the limiter
+// fails the request is let through, so an outage of a shared backend does
+// not take the API down with it.
+//
+// It must run after auth.Middleware, so clients are told apart by their API
+// key or user, falling back to their IP when authentication is disabled.
+// Requests auth.Middleware rejects never get here; IPMiddleware limits
+// those.
+func Middleware(limiter Limiter, policies map[Group]Policy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			group := GroupOf(r)
+			policy, ok := policies[group]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if allow(w, r, limiter, string(group)+"|"+clientKey(r), policy, string(group)+" requests") {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// IPMiddleware limits each IP address to policy across all routes, the
+// same way Middleware limits a client. It must run before auth.Middleware,
+// so requests without valid credentials are limited too, along with the
+// key lookups that bogus API keys cost.
+func IPMiddleware(limiter Limiter, policy Policy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if allow(w, r, limiter, "ip|"+clientIP(r), policy, "requests from one address") {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allow takes a token from the bucket of key and sets the RateLimit
+// headers. It reports whether the request may go on; if not, it has
+// answered it with 429.
+func allow(w http.ResponseWriter, r *http.Request, limiter Limiter, key string, policy Policy, what string) bool {
+	decision, err := limiter.Allow(r.Context(), key, policy)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error while rate limiting, letting the request through", "error", err)
+		return true
+	}
+	header := w.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset)))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, seconds(policy.Window)))
+	if !decision.Allowed {
+		header.Set("Retry-After", strconv.Itoa(max(seconds(decision.RetryAfter), 1)))
+		handler.WriteProblem(w, r, http.StatusTooManyRequests, fmt.Sprintf("rate limit of %d %s per %s exceeded", policy.Limit, what, policy.Window))
+		return false
+	}
+	return true
+}
+
+// clientKey identifies the client of a request: its API key or user when
+// authenticated, otherwise its IP address.
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		return principal.Key()
+	}
+	return "ip:" + clientIP(r)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds rounds a duration up to whole seconds, as the headers need.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
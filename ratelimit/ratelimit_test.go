@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pranayyb/DriveThrough/handler"
+)
+
+// near reports whether two durations are within a microsecond, as the
+// buckets count in floating point.
+func near(got, want time.Duration) bool {
+	return (got - want).Abs() < time.Microsecond
+}
+
+func TestMemoryLimiterRefill(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	policy := Policy{Limit: 3, Window: 3 * time.Second}
+	start := time.Now()
+
+	for i := range 3 {
+		decision := limiter.take("client", policy, start)
+		if !decision.Allowed || decision.Remaining != 2-i || decision.Limit != 3 {
+			t.Fatalf("request %d got %+v, want allowed with %d remaining", i+1, decision, 2-i)
+		}
+		if !near(decision.Reset, time.Duration(i+1)*time.Second) {
+			t.Errorf("request %d resets in %v, want %v", i+1, decision.Reset, time.Duration(i+1)*time.Second)
+		}
+	}
+	denied := limiter.take("client", policy, start)
+	if denied.Allowed || denied.Remaining != 0 || !near(denied.RetryAfter, time.Second) || !near(denied.Reset, 3*time.Second) {
+		t.Fatalf("an empty bucket got %+v, want denied, retry after 1s and full after 3s", denied)
+	}
+	if other := limiter.take("other", policy, start); !other.Allowed || other.Remaining != 2 {
+		t.Errorf("another client got %+v, want its own full bucket", other)
+	}
+
+	if early := limiter.take("client", policy, start.Add(500*time.Millisecond)); early.Allowed || !near(early.RetryAfter, 500*time.Millisecond) {
+		t.Errorf("half a token in got %+v, want denied, retry after 500ms", early)
+	}
+	if refilled := limiter.take("client", policy, start.Add(time.Second)); !refilled.Allowed || refilled.Remaining != 0 {
+		t.Errorf("a token in got %+v, want allowed with none remaining", refilled)
+	}
+	if idle := limiter.take("client", policy, start.Add(time.Hour)); !idle.Allowed || idle.Remaining != 2 || !near(idle.Reset, time.Second) {
+		t.Errorf("after an idle hour got %+v, want a burst of at most 3", idle)
+	}
+}
+
+func TestMemoryLimiterInvalidPolicy(t *testing.T) {
+	for _, policy := range []Policy{{}, {Limit: 1}, {Window: time.Second}, {Limit: -1, Window: time.Second}} {
+		if _, err := NewMemoryLimiter().Allow(context.Background(), "client", policy); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("policy %+v got %v, want ErrInvalidPolicy", policy, err)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("300/1m")
+	if err != nil || policy != (Policy{Limit: 300, Window: time.Minute}) {
+		t.Errorf("got %+v, %v, want 300 per minute", policy, err)
+	}
+	for _, text := range []string{"", "300", "0/1m", "-1/1m", "ten/1m", "300/0s", "300/minute"} {
+		if _, err := ParsePolicy(text); err == nil {
+			t.Errorf("ParsePolicy(%q) accepted it", text)
+		}
+	}
+}
+
+// fixedLimiter decides every request the same way.
+type fixedLimiter struct {
+	decision Decision
+	err      error
+}
+
+func (l fixedLimiter) Allow(ctx context.Context, key string, policy Policy) (Decision, error) {
+	return l.decision, l.err
+}
+
+func serve(middleware mux.MiddlewareFunc, method string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.Use(middleware)
+	router.HandleFunc("/cars", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, "/cars", nil))
+	return rec
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	policies := map[Group]Policy{Writes: {Limit: 10, Window: time.Minute}}
+
+	t.Run("allowed", func(t *testing.T) {
+		limiter := fixedLimiter{decision: Decision{Allowed: true, Limit: 10, Remaining: 7, Reset: 17500 * time.Millisecond}}
+		rec := serve(Middleware(limiter, policies), "POST")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("got %d, want 204", rec.Code)
+		}
+		for name, want := range map[string]string{
+			"RateLimit-Limit":     "10",
+			"RateLimit-Remaining": "7",
+			"RateLimit-Reset":     "18",
+			"RateLimit-Policy":    "10;w=60",
+			"Retry-After":         "",
+		} {
+			if got := rec.Header().Get(name); got != want {
+				t.Errorf("%s is %q, want %q", name, got, want)
+			}
+		}
+	})
+	t.Run("denied", func(t *testing.T) {
+		limiter := fixedLimiter{decision: Decision{Limit: 10, Reset: time.Minute, RetryAfter: 5200 * time.Millisecond}}
+		rec := serve(Middleware(limiter, policies), "POST")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("got %d, want 429", rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "6" {
+			t.Errorf("Retry-After is %q, want 6", got)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+			t.Errorf("RateLimit-Remaining is %q, want 0", got)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Errorf("got Content-Type %q, want application/problem+json", got)
+		}
+		var problem handler.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem.Status != http.StatusTooManyRequests || problem.Detail == "" {
+			t.Errorf("got problem %+v, %v", problem, err)
+		}
+	})
+	t.Run("retry after at least a second", func(t *testing.T) {
+		limiter := fixedLimiter{decision: Decision{Limit: 10, RetryAfter: time.Millisecond}}
+		if got := serve(Middleware(limiter, policies), "POST").Header().Get("Retry-After"); got != "1" {
+			t.Errorf("Retry-After is %q, want 1", got)
+		}
+	})
+	t.Run("group without a policy", func(t *testing.T) {
+		rec := serve(Middleware(fixedLimiter{}, policies), "GET")
+		if rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("got %d with RateLimit-Limit %q, want 204 without headers", rec.Code, rec.Header().Get("RateLimit-Limit"))
+		}
+	})
+	t.Run("limiter failure lets the request through", func(t *testing.T) {
+		rec := serve(Middleware(fixedLimiter{err: errors.New("connection refused")}, policies), "POST")
+		if rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("got %d with RateLimit-Limit %q, want 204 without headers", rec.Code, rec.Header().Get("RateLimit-Limit"))
+		}
+	})
+}
+
+func TestIPMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(IPMiddleware(NewMemoryLimiter(), Policy{Limit: 2, Window: time.Minute}))
+	router.HandleFunc("/cars", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	request := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/cars", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		rec := request("192.0.2.1:" + strconv.Itoa(40000+i))
+		if rec.Code != want {
+			t.Fatalf("request %d got %d, want %d", i+1, rec.Code, want)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != strconv.Itoa(max(1-i, 0)) {
+			t.Errorf("request %d has RateLimit-Remaining %q, want %d", i+1, got, max(1-i, 0))
+		}
+		if rec.Code == http.StatusTooManyRequests {
+			retry, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			if err != nil || retry < 1 || retry > 30 {
+				t.Errorf("Retry-After is %q, want up to the 30s a token takes", rec.Header().Get("Retry-After"))
+			}
+		}
+	}
+	if rec := request("192.0.2.2:40000"); rec.Code != http.StatusNoContent {
+		t.Errorf("another address got %d, want 204", rec.Code)
+	}
+}
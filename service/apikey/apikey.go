@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/audit"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+// keyPrefix starts every key so leaked keys are easy to recognise, e.g. by
+// secret scanners.
+const keyPrefix = "dt_"
+
+// displayedKeyLength is how much of a key is kept in the clear to tell keys
+// apart.
+const displayedKeyLength = len(keyPrefix) + 8
+
+type APIKeyService struct {
+	store store.APIKeyStoreInterface
+}
+
+func NewAPIKeyService(store store.APIKeyStoreInterface) *APIKeyService {
+	return &APIKeyService{
+		store: store,
+	}
+}
+
+// IssueKey creates a key with 256 random bits. The key is only returned
+// here; afterwards only its hash is known.
+func (s *APIKeyService) IssueKey(ctx context.Context, req *models.APIKeyRequest) (*models.IssuedAPIKey, error) {
+	now := time.Now()
+	if err := models.ValidateAPIKeyRequest(*req, now); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	created, err := s.store.CreateAPIKey(ctx, models.APIKey{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Prefix:    key[:displayedKeyLength],
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: audit.ActorFrom(ctx),
+		CreatedAt: now,
+		Hash:      hashKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.IssuedAPIKey{APIKey: created, Key: key}, nil
+}
+
+func (s *APIKeyService) ListKeys(ctx context.Context) ([]models.APIKey, error) {
+	return s.store.ListAPIKeys(ctx)
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, id string) (*models.APIKey, error) {
+	key, err := s.store.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// VerifyKey returns the key a request presented, failing with a
+// models.UnauthenticatedError unless it exists, is not revoked and has not
+// expired.
+func (s *APIKeyService) VerifyKey(ctx context.Context, key string) (models.APIKey, error) {
+	found, err := s.store.GetAPIKeyByHash(ctx, hashKey(key))
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return found, models.NewUnauthenticatedError("invalid API key")
+	case err != nil:
+		return found, err
+	case found.RevokedAt != nil:
+		return found, models.NewUnauthenticatedError("the API key has been revoked")
+	case found.ExpiresAt != nil && !time.Now().Before(*found.ExpiresAt):
+		return found, models.NewUnauthenticatedError("the API key has expired")
+	}
+	return found, nil
+}
+
+// hashKey returns the SHA-256 of a key. Keys are random enough that a fast
+// unsalted hash cannot be brute forced.
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -2,63 +2,256 @@ package car
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/mergepatch"
 	"github.com/pranayyb/DriveThrough/models"
 	"github.com/pranayyb/DriveThrough/store"
 )
 
 type CarService struct {
-	store store.CarStoreInterface
+	store       store.CarStoreInterface
+	engineStore store.EngineStoreInterface
+	rates       store.ExchangeRateStoreInterface
+	tx          store.TxManager
 }
 
-func NewCarService(store store.CarStoreInterface) *CarService {
+func NewCarService(store store.CarStoreInterface, engineStore store.EngineStoreInterface, rates store.ExchangeRateStoreInterface, tx store.TxManager) *CarService {
 	return &CarService{
-		store: store,
+		store:       store,
+		engineStore: engineStore,
+		rates:       rates,
+		tx:          tx,
 	}
 }
 
-func (s *CarService) GetCarById(id string, ctx context.Context) (*models.Car, error) {
+// GetCarById returns the car with its price converted to currency, unless
+// currency is empty.
+func (s *CarService) GetCarById(id string, currency string, ctx context.Context) (*models.Car, error) {
+	prices, err := s.priceConverter(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
 	car, err := s.store.GetCarById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	prices.convert(&car)
+	return &car, nil
+}
+
+// GetCarByVIN returns the live car with the VIN, which may be given in any
+// case.
+func (s *CarService) GetCarByVIN(vin string, currency string, ctx context.Context) (*models.Car, error) {
+	vin = models.NormalizeVIN(vin)
+	if err := models.ValidateVIN(vin); err != nil {
+		return nil, err
+	}
+	prices, err := s.priceConverter(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	car, err := s.store.GetCarByVIN(ctx, vin)
+	if err != nil {
+		return nil, err
+	}
+	prices.convert(&car)
 	return &car, nil
 }
 
-func (s *CarService) GetCarByBrand(brand string, ctx context.Context, isEngine bool) ([]models.Car, error) {
-	car, err := s.store.GetCarByBrand(ctx, brand, isEngine)
+func (s *CarService) ListCars(filter models.CarFilter, ctx context.Context) (*models.CarPage, error) {
+	if err := models.ValidateCarFilter(&filter); err != nil {
+		return nil, err
+	}
+	prices, err := s.priceConverter(ctx, filter.Currency)
 	if err != nil {
 		return nil, err
 	}
-	return car, nil
+	filter.PriceMin = prices.toBase(filter.PriceMin)
+	filter.PriceMax = prices.toBase(filter.PriceMax)
+	page, err := s.store.ListCars(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Cars {
+		prices.convert(&page.Cars[i])
+	}
+	return &page, nil
 }
 
+// ExportCars returns a cursor over every car matching the filter. The
+// caller must close it.
+func (s *CarService) ExportCars(filter models.CarFilter, ctx context.Context) (store.CarCursor, error) {
+	if err := models.ValidateCarFilter(&filter); err != nil {
+		return nil, err
+	}
+	prices, err := s.priceConverter(ctx, filter.Currency)
+	if err != nil {
+		return nil, err
+	}
+	filter.PriceMin = prices.toBase(filter.PriceMin)
+	filter.PriceMax = prices.toBase(filter.PriceMax)
+	cursor, err := s.store.ExportCars(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return convertedCursor{CarCursor: cursor, prices: prices}, nil
+}
+
+// CreateCar creates a car using an existing engine, or, when the request
+// carries an inline engine spec without an id, creates that engine along
+// with the car so neither exists without the other. A brand or year left
+// out is taken from the VIN.
 func (s *CarService) CreateCar(carReq *models.CarRequest, ctx context.Context) (*models.Car, error) {
-	if err := models.ValidateRequest(*carReq); err != nil {
+	carReq.VIN = models.NormalizeVIN(carReq.VIN)
+	if err := fillFromVIN(carReq); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateCreateRequest(*carReq); err != nil {
 		return nil, err
 	}
-	car, err := s.store.CreateCar(ctx, carReq)
+	if !carReq.HasInlineEngine() {
+		car, err := s.store.CreateCar(ctx, carReq)
+		if err != nil {
+			return nil, err
+		}
+		return &car, nil
+	}
+
+	var car models.Car
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		engine, err := s.engineStore.CreateEngine(ctx, &models.EngineRequest{
+			Displacement:  carReq.Engine.Displacement,
+			NoOfCylinders: carReq.Engine.NoOfCylinders,
+			CarRange:      carReq.Engine.CarRange,
+		})
+		if err != nil {
+			return err
+		}
+		withEngine := *carReq
+		withEngine.Engine.EngineID = engine.EngineID
+		car, err = s.store.CreateCar(ctx, &withEngine)
+		if err != nil {
+			return err
+		}
+		car.Engine = engine
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &car, nil
 }
 
-func (s *CarService) UpdateCar(id string, carReq *models.CarRequest, ctx context.Context) (*models.Car, error) {
+func (s *CarService) UpdateCar(id string, carReq *models.CarRequest, version int64, ctx context.Context) (*models.Car, error) {
+	carReq.VIN = models.NormalizeVIN(carReq.VIN)
 	if err := models.ValidateRequest(*carReq); err != nil {
 		return nil, err
 	}
-	car, err := s.store.UpdateCar(ctx, id, carReq)
+	car, err := s.store.UpdateCar(ctx, id, carReq, version)
+	if err != nil {
+		return nil, err
+	}
+	return &car, nil
+}
+
+// PatchCar applies a JSON merge patch to the car, validates the merged
+// result and only writes the columns that actually changed.
+func (s *CarService) PatchCar(id string, patch []byte, version int64, ctx context.Context) (*models.Car, error) {
+	current, err := s.store.GetCarById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		// the patch was computed against what we just read, so make sure
+		// nobody changed the car before it is written back
+		version = current.Version
+	} else if version != current.Version {
+		return nil, models.NewPreconditionFailedError("car", id)
+	}
+	if err := models.ValidateCarPatch(patch); err != nil {
+		return nil, err
+	}
+	original := models.CarRequestFromCar(current)
+	var carReq models.CarRequest
+	if err := mergepatch.ApplyTo(original, patch, &carReq); err != nil {
+		return nil, models.FromDecodeError(err)
+	}
+	carReq.VIN = models.NormalizeVIN(carReq.VIN)
+	// a car detached from its deleted engine has no engine_id
+	detached := current.Engine.EngineID == uuid.Nil
+	if err := models.ValidatePatchedRequest(carReq, detached); err != nil {
+		return nil, err
+	}
+	update := models.NewCarUpdate(original, carReq)
+	if update.IsEmpty() {
+		return &current, nil
+	}
+	car, err := s.store.PatchCar(ctx, id, update, version)
+	if err != nil {
+		return nil, err
+	}
+	return &car, nil
+}
+
+func (s *CarService) DeleteCar(id string, version int64, ctx context.Context) (*models.Car, error) {
+	car, err := s.store.DeleteCar(ctx, id, version)
 	if err != nil {
 		return nil, err
 	}
 	return &car, nil
 }
 
-func (s *CarService) DeleteCar(id string, ctx context.Context) (*models.Car, error) {
-	car, err := s.store.DeleteCar(ctx, id)
+func (s *CarService) ListDeletedCars(ctx context.Context) ([]models.Car, error) {
+	return s.store.ListDeletedCars(ctx)
+}
+
+func (s *CarService) RestoreCar(id string, ctx context.Context) (*models.Car, error) {
+	car, err := s.store.RestoreCar(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return &car, nil
 }
+
+// PurgeCars permanently removes cars that have been in the trash for longer
+// than the retention period.
+func (s *CarService) PurgeCars(retention time.Duration, ctx context.Context) (*models.PurgeResult, error) {
+	if retention < 0 {
+		return nil, models.NewValidationError("older_than", models.CodeOutOfRange, "retention must not be negative")
+	}
+	deletedBefore := time.Now().Add(-retention)
+	purged, err := s.store.PurgeCars(ctx, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PurgeResult{Purged: purged, DeletedBefore: deletedBefore}, nil
+}
+
+func (s *CarService) GetCarHistory(id string, ctx context.Context) ([]models.HistoryEntry, error) {
+	return s.store.GetCarHistory(ctx, id)
+}
+
+// CountCarsByFuelType counts the cars for sale of each fuel type.
+func (s *CarService) CountCarsByFuelType(ctx context.Context) (map[string]int64, error) {
+	return s.store.CountCarsByFuelType(ctx)
+}
+
+// GetCarPrices returns the car's price history with every price converted
+// to currency, unless currency is empty. Past prices are converted at the
+// current rates.
+func (s *CarService) GetCarPrices(id string, currency string, ctx context.Context) ([]models.PricePoint, error) {
+	prices, err := s.priceConverter(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	points, err := s.store.GetCarPrices(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range points {
+		prices.convertPoint(&points[i])
+	}
+	return points, nil
+}
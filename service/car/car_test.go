@@ -0,0 +1,92 @@
+package car
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/internal/testdb"
+	"github.com/pranayyb/DriveThrough/migrations"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+	carStore "github.com/pranayyb/DriveThrough/store/car"
+	engineStore "github.com/pranayyb/DriveThrough/store/engine"
+	"github.com/pranayyb/DriveThrough/store/exchangerate"
+	"github.com/pranayyb/DriveThrough/store/memory"
+	"github.com/pranayyb/DriveThrough/store/uow"
+)
+
+// eachBackend runs test with a service on fresh memory and SQL stores. The
+// SQL run is skipped unless TEST_DATABASE_URL is set.
+func eachBackend(t *testing.T, test func(t *testing.T, s *CarService, engines store.EngineStoreInterface)) {
+	t.Run("memory", func(t *testing.T) {
+		db := memory.NewDB()
+		engines := memory.NewEngineStore(db)
+		test(t, NewCarService(memory.NewCarStore(db), engines, memory.NewExchangeRateStore(db), memory.NewTxManager(db)), engines)
+	})
+	t.Run("sql", func(t *testing.T) {
+		db := testdb.Open(t)
+		migrator, err := migrations.New(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("migrating: %v", err)
+		}
+		engines := engineStore.New(db)
+		test(t, NewCarService(carStore.New(db), engines, exchangerate.New(db), uow.New(db)), engines)
+	})
+}
+
+func inlineCarRequest() *models.CarRequest {
+	return &models.CarRequest{
+		Name:     "Civic",
+		Year:     "2023",
+		Brand:    "Honda",
+		FuelType: "Petrol",
+		Engine:   models.Engine{Displacement: 2000, NoOfCylinders: 4, CarRange: 600},
+		Price:    models.Money{Amount: 2500000, Currency: models.BaseCurrency},
+	}
+}
+
+func countEngines(t *testing.T, engines store.EngineStoreInterface) int {
+	t.Helper()
+	page, err := engines.ListEngines(context.Background(), models.EngineFilter{SortBy: models.SortByDisplacement, Limit: models.MaxPageSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(page.Engines)
+}
+
+func TestCreateCarWithInlineEngine(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s *CarService, engines store.EngineStoreInterface) {
+		ctx := context.Background()
+		car, err := s.CreateCar(inlineCarRequest(), ctx)
+		if err != nil {
+			t.Fatalf("CreateCar: %v", err)
+		}
+		engine, err := engines.GetEngineById(ctx, car.Engine.EngineID.String())
+		if err != nil {
+			t.Fatalf("the inline engine was not created: %v", err)
+		}
+		if engine.Displacement != 2000 || car.Engine.Displacement != 2000 {
+			t.Errorf("the engine has displacement %d and the car shows %d, want 2000", engine.Displacement, car.Engine.Displacement)
+		}
+	})
+}
+
+func TestCreateCarRollsBackInlineEngine(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s *CarService, engines store.EngineStoreInterface) {
+		// the engine is created first, then the car fails on a currency
+		// without an exchange rate
+		req := inlineCarRequest()
+		req.Price.Currency = "EUR"
+		_, err := s.CreateCar(req, context.Background())
+		if !errors.Is(err, models.ErrValidation) {
+			t.Fatalf("got %v, want a validation error", err)
+		}
+		if n := countEngines(t, engines); n != 0 {
+			t.Fatalf("%d engines are left behind by the failed create, want none", n)
+		}
+	})
+}
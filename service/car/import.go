@@ -0,0 +1,146 @@
+package car
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// errImportFailed aborts the unit of work of an atomic import once a row
+// fails, so the rows created before it are rolled back.
+var errImportFailed = errors.New("an import row failed")
+
+// ImportCars creates the cars of an import file through CreateCar, so they
+// go through the same validation as single creates. In atomic mode every row
+// is checked before anything is written and the rows are created in one
+// unit of work, kept only if all of them succeed; in best-effort mode each
+// row stands on its own.
+func (s *CarService) ImportCars(rows []models.ImportRow, mode string, ctx context.Context) (*models.ImportReport, error) {
+	switch mode {
+	case "":
+		mode = models.ImportModeAtomic
+	case models.ImportModeAtomic, models.ImportModeBestEffort:
+	default:
+		return nil, models.NewValidationError("mode", models.CodeInvalidChoice, "mode must be one of: atomic, best_effort")
+	}
+	if len(rows) == 0 {
+		return nil, models.NewValidationError("body", models.CodeRequired, "the import contains no rows")
+	}
+	if len(rows) > models.MaxImportRows {
+		return nil, models.NewValidationError("body", models.CodeOutOfRange, "an import may contain at most "+strconv.Itoa(models.MaxImportRows)+" rows")
+	}
+
+	report := &models.ImportReport{
+		Mode:  mode,
+		Total: len(rows),
+		Rows:  make([]models.ImportRowResult, len(rows)),
+	}
+	engines := map[uuid.UUID]*models.Engine{}
+	for i := range rows {
+		report.Rows[i].Line = rows[i].Line
+		if err := s.prepareImportRow(&rows[i], engines, ctx); err != nil {
+			return nil, err
+		}
+		if rows[i].Err != nil {
+			report.Rows[i].Errors = fieldErrors(rows[i].Err)
+			report.Failed++
+		}
+	}
+	if mode == models.ImportModeAtomic && report.Failed > 0 {
+		return report, nil
+	}
+
+	create := func(ctx context.Context) error {
+		for i, row := range rows {
+			if row.Err != nil {
+				continue
+			}
+			car, err := s.CreateCar(&row.Car, ctx)
+			if err != nil {
+				if !errors.Is(err, models.ErrValidation) && !errors.Is(err, models.ErrConflict) {
+					return err
+				}
+				report.Rows[i].Errors = fieldErrors(err)
+				report.Failed++
+				if mode == models.ImportModeAtomic {
+					return errImportFailed
+				}
+				continue
+			}
+			report.Rows[i].ID = &car.ID
+			report.Created++
+		}
+		return nil
+	}
+
+	var err error
+	if mode == models.ImportModeAtomic {
+		err = s.tx.Do(ctx, create)
+	} else {
+		err = create(ctx)
+	}
+	if errors.Is(err, errImportFailed) {
+		return rollbackReport(report), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// prepareImportRow checks that the row's engine exists and fills in its
+// specs, which import files only reference by id. Engines are looked up
+// once per import.
+func (s *CarService) prepareImportRow(row *models.ImportRow, engines map[uuid.UUID]*models.Engine, ctx context.Context) error {
+	if row.Err != nil {
+		return nil
+	}
+	engineID := row.Car.Engine.EngineID
+	engine, seen := engines[engineID]
+	if !seen && engineID != uuid.Nil {
+		found, err := s.engineStore.GetEngineById(ctx, engineID.String())
+		switch {
+		case err == nil:
+			engine = &found
+		case !errors.Is(err, models.ErrNotFound):
+			return err
+		}
+		engines[engineID] = engine
+	}
+	if engine == nil && engineID != uuid.Nil {
+		row.Err = models.NewValidationError("engine.engine_id", models.CodeNotFound, "engine_id does not reference an existing engine")
+		return nil
+	}
+	if engine != nil && row.Car.Engine.Displacement == 0 && row.Car.Engine.NoOfCylinders == 0 && row.Car.Engine.CarRange == 0 {
+		row.Car.Engine = *engine
+	}
+	row.Car.VIN = models.NormalizeVIN(row.Car.VIN)
+	if row.Err = fillFromVIN(&row.Car); row.Err != nil {
+		return nil
+	}
+	row.Err = models.ValidateRequest(row.Car)
+	return nil
+}
+
+func rollbackReport(report *models.ImportReport) *models.ImportReport {
+	report.Created = 0
+	for i := range report.Rows {
+		report.Rows[i].ID = nil
+	}
+	return report
+}
+
+func fieldErrors(err error) []models.FieldError {
+	var validationErrs models.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return validationErrs
+	}
+	var duplicateErr *models.DuplicateError
+	if errors.As(err, &duplicateErr) {
+		return []models.FieldError{{Field: duplicateErr.Field, Code: models.CodeDuplicate, Message: err.Error()}}
+	}
+	return []models.FieldError{{Code: models.CodeInvalidFormat, Message: err.Error()}}
+}
@@ -0,0 +1,79 @@
+package car
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+// priceConverter converts car prices to the currency asked for on a read,
+// using the exchange rates as they were when it was made. Without a
+// currency it leaves prices as stored.
+type priceConverter struct {
+	currency string
+	rates    map[string]*big.Rat
+}
+
+func (s *CarService) priceConverter(ctx context.Context, currency string) (*priceConverter, error) {
+	prices := &priceConverter{currency: currency, rates: map[string]*big.Rat{}}
+	if currency == "" {
+		return prices, nil
+	}
+	if !models.ValidCurrency(currency) {
+		return nil, models.NewValidationError("currency", models.CodeInvalidChoice, "currency must be a supported ISO 4217 code such as USD or EUR")
+	}
+	rates, err := s.rates.ListRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range rates {
+		prices.rates[rate.Currency] = rate.Rat()
+	}
+	if _, ok := prices.rates[currency]; !ok {
+		return nil, models.NewValidationError("currency", models.CodeNotFound, "no exchange rate is set for "+currency)
+	}
+	return prices, nil
+}
+
+// convert replaces the car's price with the converted one and keeps the
+// stored price in OriginalPrice.
+func (p *priceConverter) convert(car *models.Car) {
+	car.Price, car.OriginalPrice = p.convertPrice(car.Price)
+}
+
+// convertPoint converts a point of a car's price history the same way.
+func (p *priceConverter) convertPoint(point *models.PricePoint) {
+	point.Price, point.OriginalPrice = p.convertPrice(point.Price)
+}
+
+// convertPrice returns the price in the requested currency, and the price
+// it was converted from, if any.
+func (p *priceConverter) convertPrice(price models.Money) (models.Money, *models.Money) {
+	if p.currency == "" || price.Currency == p.currency {
+		return price, nil
+	}
+	return price.Convert(p.currency, p.rates[price.Currency], p.rates[p.currency]), &price
+}
+
+// toBase converts a price bound of a filter to the base currency, which is
+// what the stores compare against.
+func (p *priceConverter) toBase(bound *models.Money) *models.Money {
+	if bound == nil || bound.Currency == models.BaseCurrency {
+		return bound
+	}
+	return &models.Money{Amount: bound.BaseAmount(p.rates[bound.Currency]), Currency: models.BaseCurrency}
+}
+
+// convertedCursor converts the price of each car as it is read.
+type convertedCursor struct {
+	store.CarCursor
+	prices *priceConverter
+}
+
+func (c convertedCursor) Car() models.Car {
+	car := c.CarCursor.Car()
+	c.prices.convert(&car)
+	return car
+}
@@ -0,0 +1,45 @@
+package car
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/vin"
+)
+
+// fillFromVIN fills in the brand and year of a new car from its VIN when
+// they are left out, and rejects given values the VIN contradicts: a brand
+// the manufacturer does not sell under the WMI, or a year other than a
+// North American VIN's model year. Outside North America the year code is
+// only a hint, so a given year stands. An invalid VIN is left for
+// validation to report.
+func fillFromVIN(carReq *models.CarRequest) error {
+	if carReq.VIN == "" {
+		return nil
+	}
+	decoded, err := vin.Decode(carReq.VIN)
+	if err != nil {
+		return nil
+	}
+	var errs models.ValidationErrors
+	if decoded.Brand != "" {
+		switch {
+		case carReq.Brand == "":
+			carReq.Brand = decoded.Brand
+		case !slices.ContainsFunc(decoded.Brands, func(brand string) bool { return strings.EqualFold(carReq.Brand, brand) }):
+			errs = append(errs, models.FieldError{Field: "brand", Code: models.CodeMismatch, Message: "brand " + carReq.Brand + " contradicts the vin, which belongs to a " + strings.Join(decoded.Brands, " or ")})
+		}
+	}
+	if decoded.ModelYear != 0 {
+		year := strconv.Itoa(decoded.ModelYear)
+		switch {
+		case carReq.Year == "":
+			carReq.Year = year
+		case carReq.Year != year && vin.NorthAmerican(decoded.VIN):
+			errs = append(errs, models.FieldError{Field: "year", Code: models.CodeMismatch, Message: "year " + carReq.Year + " contradicts the vin, which is of model year " + year})
+		}
+	}
+	return errs.Err()
+}
@@ -0,0 +1,58 @@
+package car
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestFillFromVIN(t *testing.T) {
+	tests := []struct {
+		name      string
+		vin       string
+		brand     string
+		year      string
+		wantBrand string
+		wantYear  string
+		mismatch  []string
+	}{
+		{"no vin", "", "", "", "", "", nil},
+		{"invalid vin is left for validation", "1HGCM82634A004352", "", "", "", "", nil},
+		{"fills brand and year", "2HGFE2F53PH500123", "", "", "Honda", "2023", nil},
+		{"keeps matching values", "2HGFE2F53PH500123", "honda", "2023", "honda", "2023", nil},
+		{"main brand of a shared wmi", "JN1AZ4EH7DM430111", "", "", "Nissan", "2013", nil},
+		{"other brand of a shared wmi", "JN1AZ4EH7DM430111", "Infiniti", "2013", "Infiniti", "2013", nil},
+		{"genesis under a hyundai wmi", "KMHD84LFXJU123456", "genesis", "", "genesis", "2018", nil},
+		{"brand outside the wmi", "KMHD84LFXJU123456", "Kia", "2018", "Kia", "2018", []string{"brand"}},
+		{"north american year is enforced", "2HGFE2F53PH500123", "Honda", "2022", "Honda", "2022", []string{"year"}},
+		{"year elsewhere is a hint", "WBA5R1C04PFN12345", "BMW", "2022", "BMW", "2022", nil},
+		{"both contradicted", "5YJ3E1EAXJF000001", "Ford", "2019", "Ford", "2019", []string{"brand", "year"}},
+		{"unknown manufacturer fills the year only", "11111111111111111", "", "", "", "2001", nil},
+		{"unknown manufacturer allows any brand", "11111111111111111", "Acme", "2001", "Acme", "2001", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			carReq := &models.CarRequest{VIN: test.vin, Brand: test.brand, Year: test.year}
+			err := fillFromVIN(carReq)
+			if carReq.Brand != test.wantBrand || carReq.Year != test.wantYear {
+				t.Errorf("got brand %q and year %q, want %q and %q", carReq.Brand, carReq.Year, test.wantBrand, test.wantYear)
+			}
+			var errs models.ValidationErrors
+			if test.mismatch == nil {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				return
+			}
+			if !errors.As(err, &errs) || len(errs) != len(test.mismatch) {
+				t.Fatalf("got %v, want mismatches on %v", err, test.mismatch)
+			}
+			for i, field := range test.mismatch {
+				if errs[i].Field != field || errs[i].Code != models.CodeMismatch {
+					t.Errorf("error %d is %s on %s, want mismatch on %s", i, errs[i].Code, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,10 @@ package engine
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/mergepatch"
 	"github.com/pranayyb/DriveThrough/models"
 	"github.com/pranayyb/DriveThrough/store"
 )
@@ -23,6 +27,17 @@ func (s *EngineService) GetEngineById(ctx context.Context, id string) (*models.E
 	return &engine, nil
 }
 
+func (s *EngineService) ListEngines(ctx context.Context, filter models.EngineFilter) (*models.EnginePage, error) {
+	if err := models.ValidateEngineFilter(&filter); err != nil {
+		return nil, err
+	}
+	page, err := s.store.ListEngines(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
 func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error) {
 	if err := models.ValidateEngineRequest(*engineReq); err != nil {
 		return nil, err
@@ -34,21 +49,102 @@ func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.Engi
 	return &createdEngine, err
 }
 
-func (s *EngineService) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error) {
+func (s *EngineService) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (*models.Engine, error) {
 	if err := models.ValidateEngineRequest(*engineReq); err != nil {
 		return nil, err
 	}
-	updatedEngine, err := s.store.UpdateEngine(ctx, id, engineReq)
+	updatedEngine, err := s.store.UpdateEngine(ctx, id, engineReq, version)
 	if err != nil {
 		return nil, err
 	}
 	return &updatedEngine, nil
 }
 
-func (s *EngineService) DeleteEngine(ctx context.Context, id string) (*models.Engine, error) {
-	deletedEngine, err := s.store.DeleteEngine(ctx, id)
+// PatchEngine applies a JSON merge patch to the engine, validates the merged
+// result and only writes the columns that actually changed.
+func (s *EngineService) PatchEngine(ctx context.Context, id string, patch []byte, version int64) (*models.Engine, error) {
+	current, err := s.store.GetEngineById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		// the patch was computed against what we just read, so make sure
+		// nobody changed the engine before it is written back
+		version = current.Version
+	} else if version != current.Version {
+		return nil, models.NewPreconditionFailedError("engine", id)
+	}
+	original := models.EngineRequestFromEngine(current)
+	var engineReq models.EngineRequest
+	if err := mergepatch.ApplyTo(original, patch, &engineReq); err != nil {
+		return nil, models.FromDecodeError(err)
+	}
+	if err := models.ValidateEngineRequest(engineReq); err != nil {
+		return nil, err
+	}
+	update := models.NewEngineUpdate(original, engineReq)
+	if update.IsEmpty() {
+		return &current, nil
+	}
+	patchedEngine, err := s.store.PatchEngine(ctx, id, update, version)
+	if err != nil {
+		return nil, err
+	}
+	return &patchedEngine, nil
+}
+
+// DeleteEngine deletes the engine. By default it refuses while cars still
+// use the engine; the cascade and detach strategies delete or unlink them.
+func (s *EngineService) DeleteEngine(ctx context.Context, id string, version int64, strategy string) (*models.Engine, error) {
+	switch strategy {
+	case "":
+		strategy = models.DeleteRestrict
+	case models.DeleteRestrict, models.DeleteCascade, models.DeleteDetach:
+	default:
+		return nil, models.NewValidationError("strategy", models.CodeInvalidChoice, "strategy must be one of: restrict, cascade, detach")
+	}
+	deletedEngine, err := s.store.DeleteEngine(ctx, id, version, strategy)
 	if err != nil {
 		return nil, err
 	}
 	return &deletedEngine, nil
 }
+
+func (s *EngineService) GetEngineDependents(ctx context.Context, id string) (*models.EngineDependents, error) {
+	carIDs, err := s.store.GetEngineDependents(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	engineID, _ := uuid.Parse(id)
+	return &models.EngineDependents{EngineID: engineID, Cars: carIDs}, nil
+}
+
+func (s *EngineService) ListDeletedEngines(ctx context.Context) ([]models.Engine, error) {
+	return s.store.ListDeletedEngines(ctx)
+}
+
+func (s *EngineService) RestoreEngine(ctx context.Context, id string) (*models.Engine, error) {
+	restoredEngine, err := s.store.RestoreEngine(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &restoredEngine, nil
+}
+
+// PurgeEngines permanently removes engines that have been in the trash for
+// longer than the retention period.
+func (s *EngineService) PurgeEngines(ctx context.Context, retention time.Duration) (*models.PurgeResult, error) {
+	if retention < 0 {
+		return nil, models.NewValidationError("older_than", models.CodeOutOfRange, "retention must not be negative")
+	}
+	deletedBefore := time.Now().Add(-retention)
+	purged, err := s.store.PurgeEngines(ctx, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PurgeResult{Purged: purged, DeletedBefore: deletedBefore}, nil
+}
+
+func (s *EngineService) GetEngineHistory(ctx context.Context, id string) ([]models.HistoryEntry, error) {
+	return s.store.GetEngineHistory(ctx, id)
+}
@@ -0,0 +1,44 @@
+package exchangerate
+
+import (
+	"context"
+
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+type ExchangeRateService struct {
+	store store.ExchangeRateStoreInterface
+}
+
+func NewExchangeRateService(store store.ExchangeRateStoreInterface) *ExchangeRateService {
+	return &ExchangeRateService{
+		store: store,
+	}
+}
+
+func (s *ExchangeRateService) ListRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	return s.store.ListRates(ctx)
+}
+
+func (s *ExchangeRateService) GetRate(ctx context.Context, currency string) (*models.ExchangeRate, error) {
+	rate, err := s.store.GetRate(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// SetRate sets how many units of the currency one unit of the base currency
+// buys. Cars priced in the currency are re-priced for filtering and sorting
+// right away.
+func (s *ExchangeRateService) SetRate(ctx context.Context, currency string, req *models.ExchangeRateRequest) (*models.ExchangeRate, error) {
+	if err := models.ValidateExchangeRate(currency, *req); err != nil {
+		return nil, err
+	}
+	rate, err := s.store.SetRate(ctx, currency, req.Rate)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
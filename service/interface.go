@@ -2,21 +2,52 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
 )
 
 type CarServiceInterface interface {
-	GetCarById(id string, ctx context.Context) (*models.Car, error)
-	GetCarByBrand(brand string, ctx context.Context, isEngine bool) ([]models.Car, error)
+	GetCarById(id string, currency string, ctx context.Context) (*models.Car, error)
+	GetCarByVIN(vin string, currency string, ctx context.Context) (*models.Car, error)
+	ListCars(filter models.CarFilter, ctx context.Context) (*models.CarPage, error)
+	ExportCars(filter models.CarFilter, ctx context.Context) (store.CarCursor, error)
 	CreateCar(carReq *models.CarRequest, ctx context.Context) (*models.Car, error)
-	UpdateCar(id string, carReq *models.CarRequest, ctx context.Context) (*models.Car, error)
-	DeleteCar(id string, ctx context.Context) (*models.Car, error)
+	UpdateCar(id string, carReq *models.CarRequest, version int64, ctx context.Context) (*models.Car, error)
+	PatchCar(id string, patch []byte, version int64, ctx context.Context) (*models.Car, error)
+	DeleteCar(id string, version int64, ctx context.Context) (*models.Car, error)
+	ListDeletedCars(ctx context.Context) ([]models.Car, error)
+	RestoreCar(id string, ctx context.Context) (*models.Car, error)
+	PurgeCars(retention time.Duration, ctx context.Context) (*models.PurgeResult, error)
+	GetCarHistory(id string, ctx context.Context) ([]models.HistoryEntry, error)
+	GetCarPrices(id string, currency string, ctx context.Context) ([]models.PricePoint, error)
+	ImportCars(rows []models.ImportRow, mode string, ctx context.Context) (*models.ImportReport, error)
+	CountCarsByFuelType(ctx context.Context) (map[string]int64, error)
 }
 
 type EngineServiceInterface interface {
 	GetEngineById(ctx context.Context, id string) (*models.Engine, error)
+	ListEngines(ctx context.Context, filter models.EngineFilter) (*models.EnginePage, error)
 	CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error)
-	UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error)
-	DeleteEngine(ctx context.Context, id string) (*models.Engine, error)
+	UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (*models.Engine, error)
+	PatchEngine(ctx context.Context, id string, patch []byte, version int64) (*models.Engine, error)
+	DeleteEngine(ctx context.Context, id string, version int64, strategy string) (*models.Engine, error)
+	GetEngineDependents(ctx context.Context, id string) (*models.EngineDependents, error)
+	ListDeletedEngines(ctx context.Context) ([]models.Engine, error)
+	RestoreEngine(ctx context.Context, id string) (*models.Engine, error)
+	PurgeEngines(ctx context.Context, retention time.Duration) (*models.PurgeResult, error)
+	GetEngineHistory(ctx context.Context, id string) ([]models.HistoryEntry, error)
+}
+
+type ExchangeRateServiceInterface interface {
+	ListRates(ctx context.Context) ([]models.ExchangeRate, error)
+	GetRate(ctx context.Context, currency string) (*models.ExchangeRate, error)
+	SetRate(ctx context.Context, currency string, req *models.ExchangeRateRequest) (*models.ExchangeRate, error)
+}
+
+type APIKeyServiceInterface interface {
+	IssueKey(ctx context.Context, req *models.APIKeyRequest) (*models.IssuedAPIKey, error)
+	ListKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeKey(ctx context.Context, id string) (*models.APIKey, error)
 }
@@ -0,0 +1,94 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store/uow"
+)
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, expires_at, revoked_at, created_by, created_at`
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+func (s Store) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	return scanAPIKey(uow.Conn(ctx, s.db).QueryRowContext(ctx, `INSERT INTO api_key(id, name, prefix, key_hash, scopes, expires_at, created_by, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING `+apiKeyColumns,
+		key.ID, key.Name, key.Prefix, key.Hash, pq.Array(key.Scopes), key.ExpiresAt, key.CreatedBy, key.CreatedAt))
+}
+
+// ListAPIKeys returns every key, including revoked and expired ones, newest
+// first.
+func (s Store) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	rows, err := uow.Conn(ctx, s.db).QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_key ORDER BY created_at DESC, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s Store) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	key, err := scanAPIKey(uow.Conn(ctx, s.db).QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_key WHERE key_hash=$1`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, models.NewNotFoundError("api key", "")
+	}
+	return key, err
+}
+
+func (s Store) RevokeAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return models.APIKey{}, models.NewInvalidIDError("api key", id)
+	}
+	key, err := scanAPIKey(uow.Conn(ctx, s.db).QueryRowContext(ctx, `UPDATE api_key SET revoked_at = COALESCE(revoked_at, $2) WHERE id=$1
+	RETURNING `+apiKeyColumns, id, time.Now()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, models.NewNotFoundError("api key", id)
+	}
+	return key, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		pq.Array(&key.Scopes),
+		&key.ExpiresAt,
+		&key.RevokedAt,
+		&key.CreatedBy,
+		&key.CreatedAt,
+	)
+	return key, err
+}
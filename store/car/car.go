@@ -4,270 +4,805 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+	"github.com/pranayyb/DriveThrough/store/history"
+	"github.com/pranayyb/DriveThrough/store/uow"
 )
 
-type Store struct {
-	db *sql.DB
+const carWithEngineColumns = `c.id, c.name, c.brand, c.year, c.fuel_type, COALESCE(c.vin, ''), c.price_minor, c.currency, c.price_base, c.version, c.created_at, c.updated_at, c.deleted_at, e.id, e.displacement, e.no_of_cylinders, e.car_range`
+
+// sortColumns maps a sort key to its column and the cast applied to the
+// textual cursor value so postgres compares it with the right type.
+var sortColumns = map[string]struct {
+	column string
+	cast   string
+}{
+	models.SortByPrice:     {column: "c.price_base", cast: "bigint"},
+	models.SortByYear:      {column: "c.year", cast: "varchar"},
+	models.SortByCreatedAt: {column: "c.created_at", cast: "timestamp"},
 }
 
-func New(db *sql.DB) *Store {
-	return &Store{
-		db: db,
-	}
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
+// scanCarWithEngine scans carWithEngineColumns. The engine columns are NULL
+// for a car that was detached from its engine, leaving car.Engine empty.
+func scanCarWithEngine(row rowScanner) (models.Car, error) {
 	var car models.Car
-	query := `SELECT c.id, c.name, c.brand, c.year, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, e.id,e.displacement, e.no_of_cylinders, e.car_range FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE c.id=$1`
-
-	row := s.db.QueryRowContext(ctx, query, id)
+	var displacement, noOfCylinders, carRange sql.NullInt64
 	err := row.Scan(
 		&car.ID,
 		&car.Name,
 		&car.Brand,
 		&car.Year,
 		&car.FuelType,
-		&car.Engine.EngineID,
-		&car.Price,
+		&car.VIN,
+		&car.Price.Amount,
+		&car.Price.Currency,
+		&car.BasePrice,
+		&car.Version,
 		&car.CreatedAt,
 		&car.UpdatedAt,
+		&car.DeletedAt,
 		&car.Engine.EngineID,
-		&car.Engine.Displacement,
-		&car.Engine.NoOfCylinders,
-		&car.Engine.CarRange,
+		&displacement,
+		&noOfCylinders,
+		&carRange,
 	)
+	car.Engine.Displacement = displacement.Int64
+	car.Engine.NoOfCylinders = noOfCylinders.Int64
+	car.Engine.CarRange = carRange.Int64
+	return car, err
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	var car models.Car
+	if _, err := uuid.Parse(id); err != nil {
+		return car, models.NewInvalidIDError("car", id)
+	}
+	query := `SELECT ` + carWithEngineColumns + ` FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE c.id=$1 AND c.deleted_at IS NULL`
+	car, err := scanCarWithEngine(uow.Conn(ctx, s.db).QueryRowContext(ctx, query, id))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return car, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return car, models.NewNotFoundError("car", id)
 		}
 		return car, err
 	}
 	return car, nil
 }
 
-func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
-	var cars []models.Car
-	var query string
-	if isEngine {
-		query = `SELECT c.id, c.name, c.brand, c.year, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, e.id,e.displacement, e.no_of_cylinders, e.car_range FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE c.brand=$1`
-	} else {
-		query = `SELECT id,name,brand,year,fuel_type,engine_id,price,created_at,updated_at FROM car WHERE brand=$1`
+func (s Store) ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error) {
+	page := models.CarPage{Cars: []models.Car{}}
+	query, args, err := filteredCarsQuery(filter)
+	if err != nil {
+		return page, err
 	}
-	rows, err := s.db.QueryContext(ctx, query, brand)
+	// fetch one extra row to know whether another page follows
+	args = append(args, filter.Limit+1)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := uow.Conn(ctx, s.db).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return page, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var car models.Car
-		if isEngine {
-			var engine models.Engine
-			err := rows.Scan(
-				&car.ID,
-				&car.Name,
-				&car.Brand,
-				&car.Year,
-				&car.FuelType,
-				&car.Engine.EngineID,
-				&car.Price,
-				&car.CreatedAt,
-				&car.UpdatedAt,
-				&car.Engine.EngineID,
-				&car.Engine.Displacement,
-				&car.Engine.NoOfCylinders,
-				&car.Engine.CarRange,
-			)
-			if err != nil {
-				return nil, err
-			}
-			car.Engine = engine
-		} else {
-			err := rows.Scan(
-				&car.ID,
-				&car.Name,
-				&car.Brand,
-				&car.Year,
-				&car.FuelType,
-				&car.Engine.EngineID,
-				&car.Price,
-				&car.CreatedAt,
-				&car.UpdatedAt,
-			)
-			if err != nil {
-				return nil, err
-			}
+		car, err := scanCarWithEngine(rows)
+		if err != nil {
+			return page, err
 		}
-		cars = append(cars, car)
+		page.Cars = append(page.Cars, car)
 	}
 	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Cars) > filter.Limit {
+		page.Cars = page.Cars[:filter.Limit]
+		last := page.Cars[len(page.Cars)-1]
+		page.NextCursor = models.EncodeCursor(models.Cursor{
+			SortBy:     filter.SortBy,
+			Descending: filter.Descending,
+			Value:      models.CarSortValue(last, filter.SortBy),
+			ID:         last.ID,
+		})
+	}
+	return page, nil
+}
+
+// filteredCarsQuery builds the ordered query for the live cars matching the
+// filter, starting after the filter's cursor if it has one.
+func filteredCarsQuery(filter models.CarFilter) (string, []any, error) {
+	conditions := []string{"c.deleted_at IS NULL"}
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Brands) > 0 {
+		conditions = append(conditions, "c.brand = ANY("+arg(pq.Array(filter.Brands))+")")
+	}
+	if len(filter.FuelTypes) > 0 {
+		conditions = append(conditions, "c.fuel_type = ANY("+arg(pq.Array(filter.FuelTypes))+")")
+	}
+	if filter.YearMin != 0 {
+		conditions = append(conditions, "c.year >= "+arg(fmt.Sprintf("%04d", filter.YearMin)))
+	}
+	if filter.YearMax != 0 {
+		conditions = append(conditions, "c.year <= "+arg(fmt.Sprintf("%04d", filter.YearMax)))
+	}
+	if filter.PriceMin != nil {
+		conditions = append(conditions, "c.price_base >= "+arg(filter.PriceMin.Amount))
+	}
+	if filter.PriceMax != nil {
+		conditions = append(conditions, "c.price_base <= "+arg(filter.PriceMax.Amount))
+	}
+	if filter.DisplacementMin != nil {
+		conditions = append(conditions, "e.displacement >= "+arg(*filter.DisplacementMin))
+	}
+	if filter.DisplacementMax != nil {
+		conditions = append(conditions, "e.displacement <= "+arg(*filter.DisplacementMax))
+	}
+	if filter.CylindersMin != nil {
+		conditions = append(conditions, "e.no_of_cylinders >= "+arg(*filter.CylindersMin))
+	}
+	if filter.CylindersMax != nil {
+		conditions = append(conditions, "e.no_of_cylinders <= "+arg(*filter.CylindersMax))
+	}
+
+	if filter.PriceDroppedSince != nil {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM car_price p WHERE p.car_id = c.id AND p.dropped AND p.changed_at >= "+arg(*filter.PriceDroppedSince)+")")
+	}
+
+	sort := sortColumns[filter.SortBy]
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, err := models.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, c.id) %s (%s::%s, %s::uuid)",
+			sort.column, comparison, arg(cursor.Value), sort.cast, arg(cursor.ID)))
+	}
+
+	query := `SELECT ` + carWithEngineColumns + ` FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE ` + strings.Join(conditions, " AND ")
+	query += fmt.Sprintf(` ORDER BY %s %s, c.id %s`, sort.column, direction, direction)
+	return query, args, nil
+}
+
+// ExportCars streams every live car matching the filter, in the filter's
+// sort order. Limit is ignored and the rows are read as the caller advances
+// the cursor, so nothing but the current car is held in memory.
+func (s Store) ExportCars(ctx context.Context, filter models.CarFilter) (store.CarCursor, error) {
+	query, args, err := filteredCarsQuery(filter)
+	if err != nil {
 		return nil, err
 	}
-	return cars, nil
+	rows, err := uow.Conn(ctx, s.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &carRows{rows: rows}, nil
+}
+
+// carRows adapts *sql.Rows to store.CarCursor.
+type carRows struct {
+	rows *sql.Rows
+	car  models.Car
+	err  error
+}
+
+func (c *carRows) Next() bool {
+	if c.err != nil || !c.rows.Next() {
+		return false
+	}
+	c.car, c.err = scanCarWithEngine(c.rows)
+	return c.err == nil
+}
+
+func (c *carRows) Car() models.Car {
+	return c.car
+}
+
+func (c *carRows) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.rows.Err()
+}
+
+func (c *carRows) Close() error {
+	return c.rows.Close()
 }
 
 func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
-	var createdCar models.Car
-	var engineID uuid.UUID
+	return uow.InTx(ctx, s.db, func(tx *sql.Tx) (models.Car, error) {
+		if err := checkEngine(ctx, tx, carReq.Engine.EngineID); err != nil {
+			return models.Car{}, err
+		}
+		return insertCar(ctx, tx, carReq)
+	})
+}
 
-	err := s.db.QueryRowContext(ctx, "SELECT id FROM engine WHERE id=$1", carReq.Engine.EngineID).Scan(&engineID)
+// GetCarByVIN returns the live car with the given normalized VIN.
+func (s Store) GetCarByVIN(ctx context.Context, vin string) (models.Car, error) {
+	query := `SELECT ` + carWithEngineColumns + ` FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE c.vin=$1 AND c.deleted_at IS NULL`
+	car, err := scanCarWithEngine(uow.Conn(ctx, s.db).QueryRowContext(ctx, query, vin))
+	if errors.Is(err, sql.ErrNoRows) {
+		return car, models.NewNotFoundError("car with vin", vin)
+	}
+	return car, err
+}
+
+// nullableVIN stores an unknown VIN as NULL, which the unique index
+// ignores.
+func nullableVIN(vin string) sql.NullString {
+	return sql.NullString{String: vin, Valid: vin != ""}
+}
+
+// vinTaken turns a violation of the unique index on the VINs of live cars
+// into a DuplicateError.
+func vinTaken(err error, vin string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "idx_car_vin" {
+		return models.NewDuplicateError("car", "vin", vin)
+	}
+	return err
+}
+
+// checkCar fails with a NotFoundError unless the car exists and is not in
+// the trash, so a write to a missing car is reported as such before its
+// body is checked against the engines and rates. The car is only locked
+// later, after the rate, the order in which SetRate takes them too.
+func checkCar(ctx context.Context, tx *sql.Tx, id string) error {
+	var carID uuid.UUID
+	err := tx.QueryRowContext(ctx, "SELECT id FROM car WHERE id=$1 AND deleted_at IS NULL", id).Scan(&carID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.NewNotFoundError("car", id)
+	}
+	return err
+}
+
+// checkEngine fails with a validation error unless the engine exists and is
+// not in the trash.
+func checkEngine(ctx context.Context, tx *sql.Tx, engineID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, "SELECT id FROM engine WHERE id=$1 AND deleted_at IS NULL", engineID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.NewValidationError("engine.engine_id", models.CodeNotFound, "engine_id does not reference an existing engine")
+	}
+	return err
+}
+
+// basePrice converts a price to the base currency at the current rate of
+// its currency. The rate is locked for sharing, so a concurrent change of
+// the rate waits for this transaction and then re-prices its car too.
+func basePrice(ctx context.Context, tx *sql.Tx, price models.Money) (int64, error) {
+	var rate string
+	err := tx.QueryRowContext(ctx, "SELECT rate FROM exchange_rate WHERE currency=$1 FOR SHARE", price.Currency).Scan(&rate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.NewValidationError("price.currency", models.CodeNotFound, "no exchange rate is set for "+price.Currency)
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return createdCar, errors.New("engine_id not found in the database")
-		}
-		return createdCar, err
+		return 0, err
 	}
+	return price.BaseAmount(models.ExchangeRate{Rate: rate}.Rat()), nil
+}
 
-	carID := uuid.New()
+// insertCar inserts a new car and records its creation.
+func insertCar(ctx context.Context, tx *sql.Tx, carReq *models.CarRequest) (models.Car, error) {
+	var createdCar models.Car
 	createdAt := time.Now()
-	updatedAt := createdAt
-
-	newCar := models.Car{
-		ID:        carID,
-		Name:      carReq.Name,
-		Brand:     carReq.Brand,
-		Year:      carReq.Year,
-		FuelType:  carReq.FuelType,
-		Engine:    carReq.Engine,
-		Price:     carReq.Price,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
-	}
-
-	// transaction to ensure atomicity
-	tx, err := s.db.BeginTx(ctx, nil)
+	basePrice, err := basePrice(ctx, tx, carReq.Price)
 	if err != nil {
-		return createdCar, err
+		return models.Car{}, err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			return
-		}
-		err = tx.Commit()
-	}()
-
-	query := `INSERT INTO car(id,name,year,brand, fuel_type,engine_id,price,created_at,updated_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)
-	RETURNING id,name,year,brand,fuel_type,engine_id,price,created_at,updated_at`
+	query := `INSERT INTO car(id,name,year,brand, fuel_type,vin,engine_id,price_minor,currency,price_base,created_at,updated_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	RETURNING id,name,year,brand,fuel_type,COALESCE(vin, ''),engine_id,price_minor,currency,price_base,version,created_at,updated_at`
 
 	err = tx.QueryRowContext(ctx, query,
-		newCar.ID,
-		newCar.Name,
-		newCar.Brand,
-		newCar.Year,
-		newCar.FuelType,
-		newCar.Engine.EngineID,
-		newCar.Price,
-		newCar.CreatedAt,
-		newCar.UpdatedAt,
+		uuid.New(),
+		carReq.Name,
+		carReq.Year,
+		carReq.Brand,
+		carReq.FuelType,
+		nullableVIN(carReq.VIN),
+		carReq.Engine.EngineID,
+		carReq.Price.Amount,
+		carReq.Price.Currency,
+		basePrice,
+		createdAt,
+		createdAt,
 	).Scan(
 		&createdCar.ID,
 		&createdCar.Name,
 		&createdCar.Year,
 		&createdCar.Brand,
 		&createdCar.FuelType,
+		&createdCar.VIN,
 		&createdCar.Engine.EngineID,
-		&createdCar.Price,
+		&createdCar.Price.Amount,
+		&createdCar.Price.Currency,
+		&createdCar.BasePrice,
+		&createdCar.Version,
 		&createdCar.CreatedAt,
 		&createdCar.UpdatedAt,
 	)
 	if err != nil {
-		return createdCar, err
+		return models.Car{}, vinTaken(err, carReq.VIN)
+	}
+	err = history.Record(ctx, tx, "car", createdCar.ID, models.ActionCreate, nil, createdCar)
+	if err != nil {
+		return models.Car{}, err
+	}
+	err = recordPrice(ctx, tx, createdCar, false)
+	if err != nil {
+		return models.Car{}, err
 	}
 	return createdCar, nil
 }
 
-func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error) {
+func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (models.Car, error) {
 	var updatedCar models.Car
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return updatedCar, err
+	if _, err := uuid.Parse(id); err != nil {
+		return updatedCar, models.NewInvalidIDError("car", id)
 	}
-	defer func() {
+
+	return uow.InTx(ctx, s.db, func(tx *sql.Tx) (models.Car, error) {
+		if err := checkCar(ctx, tx, id); err != nil {
+			return updatedCar, err
+		}
+		if err := checkEngine(ctx, tx, carReq.Engine.EngineID); err != nil {
+			return updatedCar, err
+		}
+		basePrice, err := basePrice(ctx, tx, carReq.Price)
 		if err != nil {
-			tx.Rollback()
-			return
-		}
-		err = tx.Commit()
-	}()
-	query := `
-	UPDATED car 
-	SET name = $2, year = $3, fuel_type = $5, engine_id = $6, price = $7 updated_at = $8
-	WHERE id = $1 
-	RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at
+			return updatedCar, err
+		}
+		before, err := lockCar(ctx, tx, id)
+		if err != nil {
+			return updatedCar, err
+		}
+		query := `
+	UPDATE car
+	SET name = $2, year = $3, brand = $4, fuel_type = $5, engine_id = $6, price_minor = $7, currency = $8, price_base = $9, updated_at = $10, vin = $12, version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL AND ($11::bigint = 0 OR version = $11)
+	RETURNING id, name, year, brand, fuel_type, COALESCE(vin, ''), engine_id, price_minor, currency, price_base, version, created_at, updated_at
 	`
-	err = tx.QueryRowContext(ctx, query,
-		id,
-		carReq.Name,
-		carReq.Year,
-		carReq.Brand,
-		carReq.FuelType,
-		carReq.Engine.EngineID,
-		carReq.Price,
-		time.Now(),
-	).Scan(
-		&updatedCar.ID,
-		&updatedCar.Name,
-		&updatedCar.Year,
-		&updatedCar.Brand,
-		&updatedCar.FuelType,
-		&updatedCar.Engine.EngineID,
-		&updatedCar.Price,
-		&updatedCar.CreatedAt,
-		&updatedCar.UpdatedAt,
-	)
-	if err != nil {
-		return updatedCar, err
+		err = tx.QueryRowContext(ctx, query,
+			id,
+			carReq.Name,
+			carReq.Year,
+			carReq.Brand,
+			carReq.FuelType,
+			carReq.Engine.EngineID,
+			carReq.Price.Amount,
+			carReq.Price.Currency,
+			basePrice,
+			time.Now(),
+			version,
+			nullableVIN(carReq.VIN),
+		).Scan(
+			&updatedCar.ID,
+			&updatedCar.Name,
+			&updatedCar.Year,
+			&updatedCar.Brand,
+			&updatedCar.FuelType,
+			&updatedCar.VIN,
+			&updatedCar.Engine.EngineID,
+			&updatedCar.Price.Amount,
+			&updatedCar.Price.Currency,
+			&updatedCar.BasePrice,
+			&updatedCar.Version,
+			&updatedCar.CreatedAt,
+			&updatedCar.UpdatedAt,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.Car{}, models.NewPreconditionFailedError("car", id)
+			}
+			return updatedCar, vinTaken(err, carReq.VIN)
+		}
+		err = history.Record(ctx, tx, "car", updatedCar.ID, models.ActionUpdate, before, updatedCar)
+		if err != nil {
+			return models.Car{}, err
+		}
+		if updatedCar.Price != before.Price {
+			err = recordPrice(ctx, tx, updatedCar, updatedCar.BasePrice < before.BasePrice)
+			if err != nil {
+				return models.Car{}, err
+			}
+		}
+		return updatedCar, nil
+	})
+}
+
+func (s Store) PatchCar(ctx context.Context, id string, update models.CarUpdate, version int64) (models.Car, error) {
+	var patchedCar models.Car
+	if _, err := uuid.Parse(id); err != nil {
+		return patchedCar, models.NewInvalidIDError("car", id)
+	}
+
+	args := []any{id}
+	var assignments []string
+	set := func(column string, value any) {
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if update.Name != nil {
+		set("name", *update.Name)
+	}
+	if update.Year != nil {
+		set("year", *update.Year)
 	}
-	return updatedCar, nil
+	if update.Brand != nil {
+		set("brand", *update.Brand)
+	}
+	if update.FuelType != nil {
+		set("fuel_type", *update.FuelType)
+	}
+	if update.VIN != nil {
+		set("vin", nullableVIN(*update.VIN))
+	}
+	if update.EngineID != nil {
+		set("engine_id", *update.EngineID)
+	}
+	// the price in the base currency is only known once the rate is read
+	// in the transaction; its argument is filled in there
+	basePriceArg := -1
+	if update.Price != nil {
+		set("price_minor", update.Price.Amount)
+		set("currency", update.Price.Currency)
+		set("price_base", int64(0))
+		basePriceArg = len(args) - 1
+	}
+	set("updated_at", time.Now())
+	assignments = append(assignments, "version = version + 1")
+	args = append(args, version)
+
+	return uow.InTx(ctx, s.db, func(tx *sql.Tx) (models.Car, error) {
+		if err := checkCar(ctx, tx, id); err != nil {
+			return patchedCar, err
+		}
+		if update.EngineID != nil {
+			if err := checkEngine(ctx, tx, *update.EngineID); err != nil {
+				return patchedCar, err
+			}
+		}
+		if update.Price != nil {
+			basePrice, err := basePrice(ctx, tx, *update.Price)
+			if err != nil {
+				return patchedCar, err
+			}
+			args[basePriceArg] = basePrice
+		}
+		before, err := lockCar(ctx, tx, id)
+		if err != nil {
+			return patchedCar, err
+		}
+		query := `UPDATE car SET ` + strings.Join(assignments, ", ") + fmt.Sprintf(` WHERE id = $1 AND deleted_at IS NULL AND ($%d::bigint = 0 OR version = $%d)`, len(args), len(args)) + `
+	RETURNING id, name, year, brand, fuel_type, COALESCE(vin, ''), engine_id, price_minor, currency, price_base, version, created_at, updated_at`
+		err = tx.QueryRowContext(ctx, query, args...).Scan(
+			&patchedCar.ID,
+			&patchedCar.Name,
+			&patchedCar.Year,
+			&patchedCar.Brand,
+			&patchedCar.FuelType,
+			&patchedCar.VIN,
+			&patchedCar.Engine.EngineID,
+			&patchedCar.Price.Amount,
+			&patchedCar.Price.Currency,
+			&patchedCar.BasePrice,
+			&patchedCar.Version,
+			&patchedCar.CreatedAt,
+			&patchedCar.UpdatedAt,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.Car{}, models.NewPreconditionFailedError("car", id)
+			}
+			if update.VIN != nil {
+				err = vinTaken(err, *update.VIN)
+			}
+			return patchedCar, err
+		}
+		err = history.Record(ctx, tx, "car", patchedCar.ID, models.ActionUpdate, before, patchedCar)
+		if err != nil {
+			return models.Car{}, err
+		}
+		if patchedCar.Price != before.Price {
+			err = recordPrice(ctx, tx, patchedCar, patchedCar.BasePrice < before.BasePrice)
+			if err != nil {
+				return models.Car{}, err
+			}
+		}
+		return patchedCar, nil
+	})
 }
 
-func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+func (s Store) DeleteCar(ctx context.Context, id string, version int64) (models.Car, error) {
 	var deletedCar models.Car
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return deletedCar, err
+	if _, err := uuid.Parse(id); err != nil {
+		return deletedCar, models.NewInvalidIDError("car", id)
 	}
-	defer func() {
+	return uow.InTx(ctx, s.db, func(tx *sql.Tx) (models.Car, error) {
+		before, err := lockCar(ctx, tx, id)
 		if err != nil {
-			tx.Rollback()
-			return
-		}
-		err = tx.Commit()
-	}()
-
-	err = tx.QueryRowContext(ctx, "SELECT id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at FROM car WHERE id=$1", id).Scan(
-		&deletedCar.ID,
-		&deletedCar.Name,
-		&deletedCar.Year,
-		&deletedCar.Brand,
-		&deletedCar.FuelType,
-		&deletedCar.Engine.EngineID,
-		&deletedCar.Price,
-		&deletedCar.CreatedAt,
-		&deletedCar.UpdatedAt,
-	)
+			return models.Car{}, err
+		}
+		if version != 0 && before.Version != version {
+			return models.Car{}, models.NewPreconditionFailedError("car", id)
+		}
+		deletedAt := time.Now()
+		result, err := tx.ExecContext(ctx, "UPDATE car SET deleted_at=$2, updated_at=$2, version=version+1 WHERE id=$1", id, deletedAt)
+		if err != nil {
+			return models.Car{}, err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return models.Car{}, err
+		}
+
+		if rowsAffected == 0 {
+			return models.Car{}, models.NewNotFoundError("car", id)
+		}
+		deletedCar = before
+		deletedCar.DeletedAt = &deletedAt
+		deletedCar.UpdatedAt = deletedAt
+		deletedCar.Version++
+		err = history.Record(ctx, tx, "car", deletedCar.ID, models.ActionDelete, before, deletedCar)
+		if err != nil {
+			return models.Car{}, err
+		}
+		return deletedCar, nil
+	})
+}
 
+// ListDeletedCars returns the cars in the trash, most recently deleted first.
+func (s Store) ListDeletedCars(ctx context.Context) ([]models.Car, error) {
+	cars := []models.Car{}
+	query := `SELECT ` + carWithEngineColumns + ` FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE c.deleted_at IS NOT NULL ORDER BY c.deleted_at DESC, c.id`
+	rows, err := uow.Conn(ctx, s.db).QueryContext(ctx, query)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.Car{}, errors.New("car not found")
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		car, err := scanCarWithEngine(rows)
+		if err != nil {
+			return nil, err
 		}
-		return models.Car{}, err
+		cars = append(cars, car)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	result, err := tx.ExecContext(ctx, "DELETE FROM car WHERE id =$1", id)
+	return cars, nil
+}
+
+// RestoreCar moves a car out of the trash. A car whose engine is itself in
+// the trash cannot be restored until the engine is.
+func (s Store) RestoreCar(ctx context.Context, id string) (models.Car, error) {
+	var restoredCar models.Car
+	if _, err := uuid.Parse(id); err != nil {
+		return restoredCar, models.NewInvalidIDError("car", id)
+	}
+	return uow.InTx(ctx, s.db, func(tx *sql.Tx) (models.Car, error) {
+		var before models.Car
+		var engineDeleted bool
+		err := tx.QueryRowContext(ctx, `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, COALESCE(c.vin, ''), c.engine_id, c.price_minor, c.currency, c.price_base, c.version, c.created_at, c.updated_at, c.deleted_at, e.deleted_at IS NOT NULL
+	FROM car c LEFT JOIN engine e ON c.engine_id=e.id
+	WHERE c.id=$1 AND c.deleted_at IS NOT NULL FOR UPDATE OF c`, id).Scan(
+			&before.ID,
+			&before.Name,
+			&before.Year,
+			&before.Brand,
+			&before.FuelType,
+			&before.VIN,
+			&before.Engine.EngineID,
+			&before.Price.Amount,
+			&before.Price.Currency,
+			&before.BasePrice,
+			&before.Version,
+			&before.CreatedAt,
+			&before.UpdatedAt,
+			&before.DeletedAt,
+			&engineDeleted,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return restoredCar, models.NewNotFoundError("deleted car", id)
+			}
+			return restoredCar, err
+		}
+		if engineDeleted {
+			return restoredCar, models.NewConflictError("the car's engine is deleted, restore the engine first")
+		}
+
+		restoredCar = before
+		restoredCar.DeletedAt = nil
+		restoredCar.UpdatedAt = time.Now()
+		restoredCar.Version++
+		_, err = tx.ExecContext(ctx, "UPDATE car SET deleted_at=NULL, updated_at=$2, version=$3 WHERE id=$1", id, restoredCar.UpdatedAt, restoredCar.Version)
+		if err != nil {
+			// another car may have been listed with the VIN meanwhile
+			return models.Car{}, vinTaken(err, restoredCar.VIN)
+		}
+		err = history.Record(ctx, tx, "car", restoredCar.ID, models.ActionRestore, before, restoredCar)
+		if err != nil {
+			return models.Car{}, err
+		}
+		return restoredCar, nil
+	})
+}
+
+// PurgeCars permanently removes cars that were moved to the trash before
+// the given time and returns how many were removed.
+func (s Store) PurgeCars(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return uow.InTx(ctx, s.db, func(tx *sql.Tx) (int64, error) {
+		rows, err := tx.QueryContext(ctx, `DELETE FROM car WHERE deleted_at IS NOT NULL AND deleted_at < $1
+	RETURNING id, name, year, brand, fuel_type, COALESCE(vin, ''), engine_id, price_minor, currency, price_base, version, created_at, updated_at, deleted_at`, deletedBefore)
+		if err != nil {
+			return 0, err
+		}
+		var purged []models.Car
+		for rows.Next() {
+			var car models.Car
+			err = rows.Scan(
+				&car.ID,
+				&car.Name,
+				&car.Year,
+				&car.Brand,
+				&car.FuelType,
+				&car.VIN,
+				&car.Engine.EngineID,
+				&car.Price.Amount,
+				&car.Price.Currency,
+				&car.BasePrice,
+				&car.Version,
+				&car.CreatedAt,
+				&car.UpdatedAt,
+				&car.DeletedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return 0, err
+			}
+			purged = append(purged, car)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for _, car := range purged {
+			err = history.Record(ctx, tx, "car", car.ID, models.ActionPurge, car, nil)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return int64(len(purged)), nil
+	})
+}
+
+// GetCarHistory returns every recorded change of the car, oldest first.
+func (s Store) GetCarHistory(ctx context.Context, id string) ([]models.HistoryEntry, error) {
+	carID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Car{}, err
+		return nil, models.NewInvalidIDError("car", id)
 	}
-	rowsAffected, err := result.RowsAffected()
+	entries, err := history.List(ctx, s.db, "car", carID)
 	if err != nil {
-		return models.Car{}, err
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, models.NewNotFoundError("car", id)
+	}
+	return entries, nil
+}
+
+// recordPrice adds the car's current price to its price history.
+func recordPrice(ctx context.Context, tx *sql.Tx, car models.Car, dropped bool) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO car_price(car_id, price_minor, currency, dropped, changed_at) VALUES ($1, $2, $3, $4, $5)",
+		car.ID, car.Price.Amount, car.Price.Currency, dropped, car.UpdatedAt)
+	return err
+}
+
+// GetCarPrices returns every price the car has been listed at, oldest
+// first.
+func (s Store) GetCarPrices(ctx context.Context, id string) ([]models.PricePoint, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, models.NewInvalidIDError("car", id)
+	}
+	points := []models.PricePoint{}
+	// the history of a car in the trash is hidden along with the car
+	rows, err := uow.Conn(ctx, s.db).QueryContext(ctx, `SELECT p.price_minor, p.currency, p.dropped, p.changed_at
+	FROM car_price p JOIN car c ON c.id = p.car_id
+	WHERE p.car_id=$1 AND c.deleted_at IS NULL ORDER BY p.changed_at, p.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var point models.PricePoint
+		if err := rows.Scan(&point.Price.Amount, &point.Price.Currency, &point.Dropped, &point.ChangedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, models.NewNotFoundError("car", id)
+	}
+	return points, nil
+}
+
+func (s Store) CountCarsByFuelType(ctx context.Context) (map[string]int64, error) {
+	counts := map[string]int64{}
+	rows, err := uow.Conn(ctx, s.db).QueryContext(ctx, "SELECT fuel_type, COUNT(*) FROM car WHERE deleted_at IS NULL GROUP BY fuel_type")
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+	for rows.Next() {
+		var fuelType string
+		var count int64
+		if err := rows.Scan(&fuelType, &count); err != nil {
+			return nil, err
+		}
+		counts[fuelType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
 
-	if rowsAffected == 0 {
-		return models.Car{}, errors.New("no rows were deleted")
+// lockCar reads a live car and locks its row until the transaction ends, so
+// the snapshot recorded in the history is exactly what gets changed.
+func lockCar(ctx context.Context, tx *sql.Tx, id string) (models.Car, error) {
+	var car models.Car
+	err := tx.QueryRowContext(ctx, "SELECT id, name, year, brand, fuel_type, COALESCE(vin, ''), engine_id, price_minor, currency, price_base, version, created_at, updated_at FROM car WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id).Scan(
+		&car.ID,
+		&car.Name,
+		&car.Year,
+		&car.Brand,
+		&car.FuelType,
+		&car.VIN,
+		&car.Engine.EngineID,
+		&car.Price.Amount,
+		&car.Price.Currency,
+		&car.BasePrice,
+		&car.Version,
+		&car.CreatedAt,
+		&car.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return car, models.NewNotFoundError("car", id)
 	}
-	return deletedCar, nil
+	return car, err
 }
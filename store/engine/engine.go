@@ -5,8 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store/history"
+	"github.com/pranayyb/DriveThrough/store/uow"
 )
 
 type EngineStore struct {
@@ -21,155 +26,530 @@ func New(db *sql.DB) *EngineStore {
 
 func (e EngineStore) GetEngineById(ctx context.Context, id string) (models.Engine, error) {
 	var engine models.Engine
-	tx, err := e.db.BeginTx(ctx, nil)
-	if err != nil {
-		return engine, err
+	if _, err := uuid.Parse(id); err != nil {
+		return engine, models.NewInvalidIDError("engine", id)
 	}
-
-	defer func() {
-		if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Println("failed to rollback transaction: %w", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Println("could not commit properly: %w", cmErr)
-			}
-		}
-	}()
-	err = tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range FROM engines WHERE id=$1", id).Scan(
+	err := uow.Conn(ctx, e.db).QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range, version FROM engine WHERE id=$1 AND deleted_at IS NULL", id).Scan(
 		&engine.EngineID,
 		&engine.Displacement,
 		&engine.NoOfCylinders,
 		&engine.CarRange,
+		&engine.Version,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			fmt.Println("Failed to get engine:", err)
-			return engine, nil
+			return engine, models.NewNotFoundError("engine", id)
 		}
 	}
 	return engine, err
 }
 
-func (e EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
-	var engine models.Engine
-	tx, err := e.db.BeginTx(ctx, nil)
-	if err != nil {
-		return engine, err
+// engineSortColumns maps an engine sort key to its column.
+var engineSortColumns = map[string]string{
+	models.SortByDisplacement: "e.displacement",
+	models.SortByCylinders:    "e.no_of_cylinders",
+	models.SortByRange:        "e.car_range",
+}
+
+func (e EngineStore) ListEngines(ctx context.Context, filter models.EngineFilter) (models.EnginePage, error) {
+	page := models.EnginePage{Engines: []models.EngineSummary{}}
+	conditions := []string{"e.deleted_at IS NULL"}
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	ranges := []struct {
+		column   string
+		min, max *int64
+	}{
+		{"e.displacement", filter.DisplacementMin, filter.DisplacementMax},
+		{"e.no_of_cylinders", filter.CylindersMin, filter.CylindersMax},
+		{"e.car_range", filter.RangeMin, filter.RangeMax},
+	}
+	for _, r := range ranges {
+		if r.min != nil {
+			conditions = append(conditions, r.column+" >= "+arg(*r.min))
+		}
+		if r.max != nil {
+			conditions = append(conditions, r.column+" <= "+arg(*r.max))
+		}
 	}
 
-	defer func() {
+	column := engineSortColumns[filter.SortBy]
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, err := models.DecodeCursor(filter.Cursor)
 		if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Println("failed to rollback transaction: %w", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Println("could not commit properly: %w", cmErr)
-			}
+			return page, err
 		}
-	}()
+		conditions = append(conditions, fmt.Sprintf("(%s, e.id) %s (%s::bigint, %s::uuid)",
+			column, comparison, arg(cursor.Value), arg(cursor.ID)))
+	}
 
-	engineID := uuid.New()
+	query := `SELECT e.id, e.displacement, e.no_of_cylinders, e.car_range, e.version,
+	(SELECT COUNT(*) FROM car c WHERE c.engine_id = e.id AND c.deleted_at IS NULL)
+	FROM engine e WHERE ` + strings.Join(conditions, " AND ")
+	// fetch one extra row to know whether another page follows
+	query += fmt.Sprintf(` ORDER BY %s %s, e.id %s LIMIT %s`, column, direction, direction, arg(filter.Limit+1))
 
-	_, err = tx.ExecContext(ctx, "INSERT INTO engines(id, displacement, no_of_cylinders, car_range) VALUES ($1, $2, $3, $4)", engineID, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange)
+	rows, err := uow.Conn(ctx, e.db).QueryContext(ctx, query, args...)
 	if err != nil {
-		return models.Engine{}, err
+		return page, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var engine models.EngineSummary
+		err := rows.Scan(
+			&engine.EngineID,
+			&engine.Displacement,
+			&engine.NoOfCylinders,
+			&engine.CarRange,
+			&engine.Version,
+			&engine.CarCount,
+		)
+		if err != nil {
+			return page, err
+		}
+		page.Engines = append(page.Engines, engine)
 	}
-	engine = models.Engine{
-		EngineID:      engineID,
-		Displacement:  engineReq.Displacement,
-		NoOfCylinders: engineReq.NoOfCylinders,
-		CarRange:      engineReq.CarRange,
+	if err := rows.Err(); err != nil {
+		return page, err
 	}
-	return engine, nil
+
+	if len(page.Engines) > filter.Limit {
+		page.Engines = page.Engines[:filter.Limit]
+		last := page.Engines[len(page.Engines)-1]
+		page.NextCursor = models.EncodeCursor(models.Cursor{
+			SortBy:     filter.SortBy,
+			Descending: filter.Descending,
+			Value:      models.EngineSortValue(last.Engine, filter.SortBy),
+			ID:         last.EngineID,
+		})
+	}
+	return page, nil
 }
 
-func (e EngineStore) UpdateEngine(ctx context.Context, id string, engine *models.EngineRequest) (models.Engine, error) {
+func (e EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	var engine models.Engine
+	return uow.InTx(ctx, e.db, func(tx *sql.Tx) (models.Engine, error) {
+		engineID := uuid.New()
+
+		_, err := tx.ExecContext(ctx, "INSERT INTO engine(id, displacement, no_of_cylinders, car_range) VALUES ($1, $2, $3, $4)", engineID, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		engine = models.Engine{
+			EngineID:      engineID,
+			Displacement:  engineReq.Displacement,
+			NoOfCylinders: engineReq.NoOfCylinders,
+			CarRange:      engineReq.CarRange,
+			Version:       1,
+		}
+		err = history.Record(ctx, tx, "engine", engine.EngineID, models.ActionCreate, nil, engine)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		return engine, nil
+	})
+}
+
+func (e EngineStore) UpdateEngine(ctx context.Context, id string, engine *models.EngineRequest, version int64) (models.Engine, error) {
 	engineID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Engine{}, fmt.Errorf("invalid engine id: %w", err)
+		return models.Engine{}, models.NewInvalidIDError("engine", id)
 	}
-	tx, err := e.db.BeginTx(ctx, nil)
-	if err != nil {
-		return models.Engine{}, err
+	return uow.InTx(ctx, e.db, func(tx *sql.Tx) (models.Engine, error) {
+		before, err := lockEngine(ctx, tx, id)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		var engineUpdated models.Engine
+		err = tx.QueryRowContext(ctx, `UPDATE engine SET displacement=$2, no_of_cylinders=$3, car_range=$4, updated_at=$5, version=version+1
+	WHERE id=$1 AND deleted_at IS NULL AND ($6::bigint = 0 OR version = $6)
+	RETURNING id, displacement, no_of_cylinders, car_range, version`,
+			engineID, engine.Displacement, engine.NoOfCylinders, engine.CarRange, time.Now(), version,
+		).Scan(
+			&engineUpdated.EngineID,
+			&engineUpdated.Displacement,
+			&engineUpdated.NoOfCylinders,
+			&engineUpdated.CarRange,
+			&engineUpdated.Version,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.Engine{}, models.NewPreconditionFailedError("engine", id)
+			}
+			return models.Engine{}, err
+		}
+		err = history.Record(ctx, tx, "engine", engineUpdated.EngineID, models.ActionUpdate, before, engineUpdated)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		return engineUpdated, nil
+	})
+}
+
+func (e EngineStore) PatchEngine(ctx context.Context, id string, update models.EngineUpdate, version int64) (models.Engine, error) {
+	var engine models.Engine
+	if _, err := uuid.Parse(id); err != nil {
+		return engine, models.NewInvalidIDError("engine", id)
+	}
+
+	args := []any{id}
+	var assignments []string
+	set := func(column string, value any) {
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
 	}
-	defer func() {
+	if update.Displacement != nil {
+		set("displacement", *update.Displacement)
+	}
+	if update.NoOfCylinders != nil {
+		set("no_of_cylinders", *update.NoOfCylinders)
+	}
+	if update.CarRange != nil {
+		set("car_range", *update.CarRange)
+	}
+	set("updated_at", time.Now())
+	assignments = append(assignments, "version = version + 1")
+	args = append(args, version)
+
+	return uow.InTx(ctx, e.db, func(tx *sql.Tx) (models.Engine, error) {
+		before, err := lockEngine(ctx, tx, id)
 		if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Println("failed to rollback transaction: %w", rbErr)
+			return models.Engine{}, err
+		}
+		query := "UPDATE engine SET " + strings.Join(assignments, ", ") +
+			fmt.Sprintf(" WHERE id=$1 AND deleted_at IS NULL AND ($%d::bigint = 0 OR version = $%d)", len(args), len(args)) +
+			" RETURNING id, displacement, no_of_cylinders, car_range, version"
+		err = tx.QueryRowContext(ctx, query, args...).Scan(
+			&engine.EngineID,
+			&engine.Displacement,
+			&engine.NoOfCylinders,
+			&engine.CarRange,
+			&engine.Version,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.Engine{}, models.NewPreconditionFailedError("engine", id)
 			}
-			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Println("could not commit properly: %w", cmErr)
+			return models.Engine{}, err
+		}
+		err = history.Record(ctx, tx, "engine", engine.EngineID, models.ActionUpdate, before, engine)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		return engine, nil
+	})
+}
+
+// DeleteEngine moves the engine to the trash. The live cars still using it
+// are handled according to strategy, in the same transaction: restrict
+// fails with the list of cars, cascade deletes them and detach unlinks them.
+func (e EngineStore) DeleteEngine(ctx context.Context, id string, version int64, strategy string) (models.Engine, error) {
+	var engine models.Engine
+	if _, err := uuid.Parse(id); err != nil {
+		return engine, models.NewInvalidIDError("engine", id)
+	}
+
+	return uow.InTx(ctx, e.db, func(tx *sql.Tx) (models.Engine, error) {
+		before, err := lockEngine(ctx, tx, id)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		if version != 0 && before.Version != version {
+			return models.Engine{}, models.NewPreconditionFailedError("engine", id)
+		}
+
+		dependents, err := lockDependentCars(ctx, tx, id)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		deletedAt := time.Now()
+		if len(dependents) > 0 {
+			err = releaseDependentCars(ctx, tx, id, dependents, strategy, deletedAt)
+			if err != nil {
+				return models.Engine{}, err
 			}
 		}
-	}()
 
-	results, err := tx.ExecContext(ctx, "UPDATE engine SET displacement=$2, no_of_cylinders=$3, car_range=$4 WHERE id=$1", engineID, engine.Displacement, engine.NoOfCylinders, engine.CarRange)
+		result, err := tx.ExecContext(ctx, "UPDATE engine SET deleted_at=$2, updated_at=$2, version=version+1 WHERE id=$1", id, deletedAt)
+		if err != nil {
+			return models.Engine{}, err
+		}
 
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return models.Engine{}, err
+		}
+		if rowsAffected == 0 {
+			return models.Engine{}, models.NewNotFoundError("engine", id)
+		}
+
+		engine = before
+		engine.DeletedAt = &deletedAt
+		engine.Version++
+		err = history.Record(ctx, tx, "engine", engine.EngineID, models.ActionDelete, before, engine)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		return engine, nil
+	})
+}
+
+// GetEngineDependents lists the live cars using a live engine, which are
+// the cars a delete of the engine would affect.
+func (e EngineStore) GetEngineDependents(ctx context.Context, id string) ([]uuid.UUID, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, models.NewInvalidIDError("engine", id)
+	}
+	var exists bool
+	err := uow.Conn(ctx, e.db).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM engine WHERE id=$1 AND deleted_at IS NULL)", id).Scan(&exists)
 	if err != nil {
-		return models.Engine{}, err
+		return nil, err
+	}
+	if !exists {
+		return nil, models.NewNotFoundError("engine", id)
 	}
 
-	rowAffected, err := results.RowsAffected()
+	carIDs := []uuid.UUID{}
+	rows, err := uow.Conn(ctx, e.db).QueryContext(ctx, "SELECT id FROM car WHERE engine_id=$1 AND deleted_at IS NULL ORDER BY id", id)
 	if err != nil {
-		return models.Engine{}, err
+		return nil, err
 	}
-
-	if rowAffected == 0 {
-		return models.Engine{}, fmt.Errorf("no rows were updated")
+	defer rows.Close()
+	for rows.Next() {
+		var carID uuid.UUID
+		if err := rows.Scan(&carID); err != nil {
+			return nil, err
+		}
+		carIDs = append(carIDs, carID)
 	}
-	engineUpdated := models.Engine{
-		EngineID:      engineID,
-		Displacement:  engine.Displacement,
-		NoOfCylinders: engine.NoOfCylinders,
-		CarRange:      engine.CarRange,
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return engineUpdated, nil
+	return carIDs, nil
 }
-func (e EngineStore) DeleteEngine(ctx context.Context, id string) (models.Engine, error) {
-	var engine models.Engine
 
-	tx, err := e.db.BeginTx(ctx, nil)
+// ListDeletedEngines returns the engines in the trash, most recently deleted first.
+func (e EngineStore) ListDeletedEngines(ctx context.Context) ([]models.Engine, error) {
+	engines := []models.Engine{}
+	rows, err := uow.Conn(ctx, e.db).QueryContext(ctx, `SELECT id, displacement, no_of_cylinders, car_range, version, deleted_at FROM engine
+	WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`)
 	if err != nil {
-		return models.Engine{}, err
+		return nil, err
 	}
-	defer func() {
+	defer rows.Close()
+	for rows.Next() {
+		var engine models.Engine
+		err := rows.Scan(
+			&engine.EngineID,
+			&engine.Displacement,
+			&engine.NoOfCylinders,
+			&engine.CarRange,
+			&engine.Version,
+			&engine.DeletedAt,
+		)
 		if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Println("failed to rollback transaction: %w", rbErr)
+			return nil, err
+		}
+		engines = append(engines, engine)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return engines, nil
+}
+
+func (e EngineStore) RestoreEngine(ctx context.Context, id string) (models.Engine, error) {
+	var engine models.Engine
+	if _, err := uuid.Parse(id); err != nil {
+		return engine, models.NewInvalidIDError("engine", id)
+	}
+	return uow.InTx(ctx, e.db, func(tx *sql.Tx) (models.Engine, error) {
+		var before models.Engine
+		err := tx.QueryRowContext(ctx, `SELECT id, displacement, no_of_cylinders, car_range, version, deleted_at FROM engine
+	WHERE id=$1 AND deleted_at IS NOT NULL FOR UPDATE`, id).Scan(
+			&before.EngineID,
+			&before.Displacement,
+			&before.NoOfCylinders,
+			&before.CarRange,
+			&before.Version,
+			&before.DeletedAt,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return engine, models.NewNotFoundError("deleted engine", id)
 			}
-			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Println("could not commit properly: %w", cmErr)
+			return engine, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE engine SET deleted_at=NULL, updated_at=$2, version=version+1 WHERE id=$1", id, time.Now())
+		if err != nil {
+			return models.Engine{}, err
+		}
+		engine = before
+		engine.DeletedAt = nil
+		engine.Version++
+		err = history.Record(ctx, tx, "engine", engine.EngineID, models.ActionRestore, before, engine)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		return engine, nil
+	})
+}
+
+// PurgeEngines permanently removes engines that were moved to the trash
+// before the given time. Engines still referenced by a car, even one in the
+// trash, are kept until that car is purged.
+func (e EngineStore) PurgeEngines(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return uow.InTx(ctx, e.db, func(tx *sql.Tx) (int64, error) {
+		rows, err := tx.QueryContext(ctx, `DELETE FROM engine WHERE deleted_at IS NOT NULL AND deleted_at < $1
+	AND NOT EXISTS (SELECT 1 FROM car WHERE car.engine_id = engine.id)
+	RETURNING id, displacement, no_of_cylinders, car_range, version, deleted_at`, deletedBefore)
+		if err != nil {
+			return 0, err
+		}
+		var purged []models.Engine
+		for rows.Next() {
+			var engine models.Engine
+			err = rows.Scan(
+				&engine.EngineID,
+				&engine.Displacement,
+				&engine.NoOfCylinders,
+				&engine.CarRange,
+				&engine.Version,
+				&engine.DeletedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return 0, err
 			}
+			purged = append(purged, engine)
 		}
-	}()
-	err = tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range FROM engines WHERE id=$1", id).Scan(
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for _, engine := range purged {
+			err = history.Record(ctx, tx, "engine", engine.EngineID, models.ActionPurge, engine, nil)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return int64(len(purged)), nil
+	})
+}
+
+// GetEngineHistory returns every recorded change of the engine, oldest first.
+func (e EngineStore) GetEngineHistory(ctx context.Context, id string) ([]models.HistoryEntry, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, models.NewInvalidIDError("engine", id)
+	}
+	entries, err := history.List(ctx, e.db, "engine", engineID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, models.NewNotFoundError("engine", id)
+	}
+	return entries, nil
+}
+
+// lockEngine reads a live engine and locks its row until the transaction
+// ends, so the snapshot recorded in the history is exactly what gets changed.
+func lockEngine(ctx context.Context, tx *sql.Tx, id string) (models.Engine, error) {
+	var engine models.Engine
+	err := tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range, version FROM engine WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id).Scan(
 		&engine.EngineID,
 		&engine.Displacement,
 		&engine.NoOfCylinders,
 		&engine.CarRange,
+		&engine.Version,
 	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			fmt.Println("Failed to get engine:", err)
-			return engine, nil
-		}
+	if errors.Is(err, sql.ErrNoRows) {
+		return engine, models.NewNotFoundError("engine", id)
 	}
+	return engine, err
+}
 
-	result, err := tx.ExecContext(ctx, "DELETE FROM engines WHERE id=$1", id)
+// lockDependentCars reads and locks the live cars using the engine, with
+// the same columns as the car store's lockCar, since they are recorded in
+// the history the same way.
+func lockDependentCars(ctx context.Context, tx *sql.Tx, id string) ([]models.Car, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, name, year, brand, fuel_type, COALESCE(vin, ''), engine_id, price_minor, currency, price_base, version, created_at, updated_at
+	FROM car WHERE engine_id=$1 AND deleted_at IS NULL ORDER BY id FOR UPDATE`, id)
 	if err != nil {
-		return models.Engine{}, err
+		return nil, err
+	}
+	defer rows.Close()
+	var cars []models.Car
+	for rows.Next() {
+		var car models.Car
+		err := rows.Scan(
+			&car.ID,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.FuelType,
+			&car.VIN,
+			&car.Engine.EngineID,
+			&car.Price.Amount,
+			&car.Price.Currency,
+			&car.BasePrice,
+			&car.Version,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		cars = append(cars, car)
 	}
+	return cars, rows.Err()
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return models.Engine{}, err
+// releaseDependentCars applies the delete strategy to the cars still using
+// the engine and records the change of each car in its history.
+func releaseDependentCars(ctx context.Context, tx *sql.Tx, id string, cars []models.Car, strategy string, now time.Time) error {
+	var query, action string
+	switch strategy {
+	case models.DeleteCascade:
+		query = "UPDATE car SET deleted_at=$2, updated_at=$2, version=version+1 WHERE engine_id=$1 AND deleted_at IS NULL"
+		action = models.ActionDelete
+	case models.DeleteDetach:
+		query = "UPDATE car SET engine_id=NULL, updated_at=$2, version=version+1 WHERE engine_id=$1 AND deleted_at IS NULL"
+		action = models.ActionUpdate
+	default:
+		carIDs := make([]uuid.UUID, len(cars))
+		for i, car := range cars {
+			carIDs[i] = car.ID
+		}
+		return models.NewDependentsError("engine", id, carIDs)
 	}
-	if rowsAffected == 0 {
-		return models.Engine{}, fmt.Errorf("no rows were deleted")
+	if _, err := tx.ExecContext(ctx, query, id, now); err != nil {
+		return err
 	}
 
-	return engine, nil
+	for _, before := range cars {
+		after := before
+		after.UpdatedAt = now
+		after.Version++
+		if strategy == models.DeleteCascade {
+			after.DeletedAt = &now
+		} else {
+			after.Engine = models.Engine{}
+		}
+		if err := history.Record(ctx, tx, "car", after.ID, action, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/internal/testdb"
+	"github.com/pranayyb/DriveThrough/migrations"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store/history"
+)
+
+func TestDeleteEngineRecordsDependentCarVIN(t *testing.T) {
+	for _, strategy := range []string{models.DeleteCascade, models.DeleteDetach} {
+		t.Run(strategy, func(t *testing.T) {
+			db := testdb.Open(t)
+			ctx := context.Background()
+			migrator, err := migrations.New(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Up(ctx); err != nil {
+				t.Fatalf("migrating: %v", err)
+			}
+
+			engineID, carID := uuid.New(), uuid.New()
+			const vin = "2HGFE2F53PH500123"
+			if _, err := db.ExecContext(ctx, "INSERT INTO engine (id, displacement, no_of_cylinders, car_range) VALUES ($1, 2000, 4, 600)", engineID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.ExecContext(ctx, `INSERT INTO car (id, name, year, brand, fuel_type, vin, engine_id, price_minor, currency, price_base)
+				VALUES ($1, 'Civic', '2023', 'Honda', 'Petrol', $2, $3, 2500000, 'USD', 2500000)`, carID, vin, engineID); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := New(db).DeleteEngine(ctx, engineID.String(), 0, strategy); err != nil {
+				t.Fatalf("DeleteEngine: %v", err)
+			}
+
+			entries, err := history.List(ctx, db, "car", carID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) == 0 {
+				t.Fatal("no history was recorded for the dependent car")
+			}
+			last := entries[len(entries)-1]
+			for name, snapshot := range map[string]json.RawMessage{"before": last.Before, "after": last.After} {
+				var car models.Car
+				if err := json.Unmarshal(snapshot, &car); err != nil {
+					t.Fatalf("decoding %s snapshot: %v", name, err)
+				}
+				if car.VIN != vin {
+					t.Errorf("%s snapshot has vin %q, want %q", name, car.VIN, vin)
+				}
+			}
+		})
+	}
+}
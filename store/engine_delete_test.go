@@ -0,0 +1,129 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// engineWithCars creates an engine used by two live cars and one in the
+// trash, and returns it with the ids of the live cars in id order.
+func engineWithCars(t *testing.T, s stores) (models.Engine, []uuid.UUID) {
+	t.Helper()
+	engine := createEngine(t, s)
+	cars := []uuid.UUID{createCar(t, s, engine.EngineID).ID, createCar(t, s, engine.EngineID).ID}
+	slices.SortFunc(cars, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	trashed := createCar(t, s, engine.EngineID)
+	if _, err := s.cars.DeleteCar(context.Background(), trashed.ID.String(), 0); err != nil {
+		t.Fatal(err)
+	}
+	return engine, cars
+}
+
+func TestDeleteEngineRestrict(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine, cars := engineWithCars(t, s)
+		id := engine.EngineID.String()
+
+		dependents, err := s.engines.GetEngineDependents(ctx, id)
+		if err != nil {
+			t.Fatalf("GetEngineDependents: %v", err)
+		}
+		if !slices.Equal(dependents, cars) {
+			t.Fatalf("got dependents %v, want the live cars %v", dependents, cars)
+		}
+
+		for _, strategy := range []string{models.DeleteRestrict, ""} {
+			_, err = s.engines.DeleteEngine(ctx, id, 0, strategy)
+			var dependentsErr *models.DependentsError
+			if !errors.As(err, &dependentsErr) {
+				t.Fatalf("deleting an engine in use with strategy %q got %v, want a DependentsError", strategy, err)
+			}
+			if !slices.Equal(dependentsErr.Dependents, cars) {
+				t.Errorf("the error lists %v, want %v", dependentsErr.Dependents, cars)
+			}
+		}
+		if _, err := s.engines.GetEngineById(ctx, id); err != nil {
+			t.Fatalf("the refused delete removed the engine: %v", err)
+		}
+	})
+}
+
+func TestDeleteEngineCascade(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine, cars := engineWithCars(t, s)
+		if _, err := s.engines.DeleteEngine(ctx, engine.EngineID.String(), 0, models.DeleteCascade); err != nil {
+			t.Fatalf("DeleteEngine: %v", err)
+		}
+
+		for _, car := range cars {
+			_, err := s.cars.GetCarById(ctx, car.String())
+			wantErr(t, err, models.ErrNotFound)
+		}
+		trash, err := s.cars.ListDeletedCars(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash) != 3 {
+			t.Fatalf("the trash holds %d cars, want the 2 cascaded and the one already there", len(trash))
+		}
+		entries, err := s.cars.GetCarHistory(ctx, cars[0].String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := entries[len(entries)-1]; last.Action != models.ActionDelete {
+			t.Errorf("the cascaded car's last history entry is %s, want delete", last.Action)
+		}
+	})
+}
+
+func TestDeleteEngineDetach(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine, cars := engineWithCars(t, s)
+		if _, err := s.engines.DeleteEngine(ctx, engine.EngineID.String(), 0, models.DeleteDetach); err != nil {
+			t.Fatalf("DeleteEngine: %v", err)
+		}
+
+		for _, id := range cars {
+			car, err := s.cars.GetCarById(ctx, id.String())
+			if err != nil {
+				t.Fatalf("the detached car cannot be read: %v", err)
+			}
+			if car.Engine.EngineID != uuid.Nil {
+				t.Errorf("the detached car still uses engine %s", car.Engine.EngineID)
+			}
+			if car.Version != 2 {
+				t.Errorf("the detached car has version %d, want 2", car.Version)
+			}
+		}
+		_, err := s.engines.GetEngineById(ctx, engine.EngineID.String())
+		wantErr(t, err, models.ErrNotFound)
+	})
+}
+
+func TestDeleteUnusedEngine(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		dependents, err := s.engines.GetEngineDependents(ctx, engine.EngineID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dependents) != 0 {
+			t.Fatalf("a new engine has dependents %v", dependents)
+		}
+		if _, err := s.engines.DeleteEngine(ctx, engine.EngineID.String(), 0, models.DeleteRestrict); err != nil {
+			t.Fatalf("deleting an unused engine: %v", err)
+		}
+		_, err = s.engines.GetEngineDependents(ctx, engine.EngineID.String())
+		wantErr(t, err, models.ErrNotFound)
+	})
+}
@@ -0,0 +1,109 @@
+package exchangerate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store/uow"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+func (s Store) ListRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	rates := []models.ExchangeRate{}
+	rows, err := uow.Conn(ctx, s.db).QueryContext(ctx, "SELECT currency, rate, updated_at FROM exchange_rate ORDER BY currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rate, err := scanRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (s Store) GetRate(ctx context.Context, currency string) (models.ExchangeRate, error) {
+	rate, err := scanRate(uow.Conn(ctx, s.db).QueryRowContext(ctx, "SELECT currency, rate, updated_at FROM exchange_rate WHERE currency=$1", currency))
+	if errors.Is(err, sql.ErrNoRows) {
+		return rate, models.NewNotFoundError("exchange rate", currency)
+	}
+	return rate, err
+}
+
+// SetRate creates or replaces the rate of a currency and recomputes the
+// base price of every car priced in it, including those in the trash.
+func (s Store) SetRate(ctx context.Context, currency string, rate string) (models.ExchangeRate, error) {
+	return uow.InTx(ctx, s.db, func(tx *sql.Tx) (models.ExchangeRate, error) {
+		saved, err := scanRate(tx.QueryRowContext(ctx, `INSERT INTO exchange_rate(currency, rate, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at
+		RETURNING currency, rate, updated_at`, currency, rate, time.Now()))
+		if err != nil {
+			return models.ExchangeRate{}, err
+		}
+
+		rows, err := tx.QueryContext(ctx, "SELECT id, price_minor FROM car WHERE currency=$1 FOR UPDATE", currency)
+		if err != nil {
+			return models.ExchangeRate{}, err
+		}
+		var ids []uuid.UUID
+		var basePrices []int64
+		for rows.Next() {
+			var id uuid.UUID
+			price := models.Money{Currency: currency}
+			if err := rows.Scan(&id, &price.Amount); err != nil {
+				rows.Close()
+				return models.ExchangeRate{}, err
+			}
+			ids = append(ids, id)
+			basePrices = append(basePrices, price.BaseAmount(saved.Rat()))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return models.ExchangeRate{}, err
+		}
+
+		if len(ids) > 0 {
+			_, err = tx.ExecContext(ctx, `UPDATE car SET price_base = v.price_base
+			FROM unnest($1::uuid[], $2::bigint[]) AS v(id, price_base) WHERE car.id = v.id`,
+				pq.Array(ids), pq.Array(basePrices))
+			if err != nil {
+				return models.ExchangeRate{}, err
+			}
+		}
+		return saved, nil
+	})
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRate(row rowScanner) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := row.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+	if err != nil {
+		return rate, err
+	}
+	rate.Rate = models.FormatRate(rate.Rat())
+	return rate, nil
+}
@@ -0,0 +1,75 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/audit"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store/uow"
+)
+
+// Record appends a history entry for a mutation. It must run in the same
+// transaction as the mutation so the two commit or roll back together.
+func Record(ctx context.Context, tx *sql.Tx, entityType string, entityID uuid.UUID, action string, before, after any) error {
+	entry, err := audit.NewEntry(ctx, entityType, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO audit_log(entity_type, entity_id, action, actor, before, after, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		entry.EntityType,
+		entry.EntityID,
+		entry.Action,
+		entry.Actor,
+		nullableJSON(entry.Before),
+		nullableJSON(entry.After),
+		entry.CreatedAt,
+	)
+	return err
+}
+
+// List returns the history of an entity, oldest entry first. Inside a unit
+// of work it reads through the unit's transaction, so it sees the entries
+// recorded by the unit so far.
+func List(ctx context.Context, db *sql.DB, entityType string, entityID uuid.UUID) ([]models.HistoryEntry, error) {
+	entries := []models.HistoryEntry{}
+	rows, err := uow.Conn(ctx, db).QueryContext(ctx, `SELECT id, entity_type, entity_id, action, actor, before, after, created_at
+	FROM audit_log WHERE entity_type=$1 AND entity_id=$2 ORDER BY id`, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry models.HistoryEntry
+		var before, after []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.EntityType,
+			&entry.EntityID,
+			&entry.Action,
+			&entry.Actor,
+			&before,
+			&after,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func nullableJSON(document []byte) any {
+	if document == nil {
+		return nil
+	}
+	return string(document)
+}
@@ -0,0 +1,60 @@
+package store_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestHistory(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		car := createCar(t, s, engine.EngineID)
+		if _, err := s.cars.DeleteCar(ctx, car.ID.String(), 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.cars.RestoreCar(ctx, car.ID.String()); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := s.cars.GetCarHistory(ctx, car.ID.String())
+		if err != nil {
+			t.Fatalf("GetCarHistory: %v", err)
+		}
+		var actions []string
+		for _, entry := range entries {
+			actions = append(actions, entry.Action)
+		}
+		want := []string{models.ActionCreate, models.ActionDelete, models.ActionRestore}
+		if !slices.Equal(actions, want) {
+			t.Fatalf("got history %v, want %v", actions, want)
+		}
+	})
+}
+
+func TestHistoryInsideUnitOfWork(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		engine := createEngine(t, s)
+		err := s.tx.Do(context.Background(), func(ctx context.Context) error {
+			car, err := s.cars.CreateCar(ctx, carRequest(engine.EngineID))
+			if err != nil {
+				return err
+			}
+			entries, err := s.cars.GetCarHistory(ctx, car.ID.String())
+			if err != nil {
+				t.Errorf("the unit cannot read the history it recorded: %v", err)
+				return nil
+			}
+			if len(entries) != 1 || entries[0].Action != models.ActionCreate {
+				t.Errorf("got %d history entries, want the create", len(entries))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+func (s Store) Reserve(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	now := time.Now()
+	// an expired key, or one whose request outlived its lease, is taken
+	// over in place, as if it had never been used
+	var key string
+	err := s.db.QueryRowContext(ctx, `INSERT INTO idempotency_key(principal, scope, key, request_hash, created_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (principal, scope, key) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash, status = NULL, content_type = NULL, response = NULL, created_at = EXCLUDED.created_at
+	WHERE idempotency_key.created_at < $6 OR (idempotency_key.status IS NULL AND idempotency_key.created_at < $7)
+	RETURNING key`,
+		record.Principal, record.Scope, record.Key, record.RequestHash, now, now.Add(-models.IdempotencyKeyTTL), now.Add(-models.IdempotencyLease),
+	).Scan(&key)
+	if err == nil {
+		record.CreatedAt = now
+		return record, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return record, false, err
+	}
+
+	var existing models.IdempotencyRecord
+	var status sql.NullInt64
+	var contentType sql.NullString
+	err = s.db.QueryRowContext(ctx, `SELECT principal, scope, key, request_hash, status, content_type, response, created_at
+	FROM idempotency_key WHERE principal=$1 AND scope=$2 AND key=$3`, record.Principal, record.Scope, record.Key).Scan(
+		&existing.Principal,
+		&existing.Scope,
+		&existing.Key,
+		&existing.RequestHash,
+		&status,
+		&contentType,
+		&existing.Body,
+		&existing.CreatedAt,
+	)
+	if err != nil {
+		return existing, false, err
+	}
+	existing.Status = int(status.Int64)
+	existing.ContentType = contentType.String
+	return existing, false, nil
+}
+
+func (s Store) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE idempotency_key SET status=$4, content_type=$5, response=$6 WHERE principal=$1 AND scope=$2 AND key=$3",
+		record.Principal, record.Scope, record.Key, record.Status, record.ContentType, record.Body)
+	return err
+}
+
+func (s Store) Release(ctx context.Context, principal, scope, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE principal=$1 AND scope=$2 AND key=$3 AND status IS NULL", principal, scope, key)
+	return err
+}
@@ -2,20 +2,105 @@ package store
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/pranayyb/DriveThrough/models"
 )
 
+// Methods taking a version perform a compare-and-swap: they fail with
+// models.ErrPreconditionFailed unless the stored version matches. A version
+// of 0 skips the check.
+//
+// Deletes are soft: deleted rows are hidden from every read until they are
+// restored, or removed for good by a purge.
+//
+// Every mutation appends an entry to the audit history in the same
+// transaction, attributed to the actor carried by the context.
+//
+// Store calls made inside a unit of work started by a TxManager share its
+// transaction instead of each committing on their own.
+
+// CarCursor walks over a result set one car at a time, in the manner of
+// sql.Rows. It must be closed once the caller is done with it.
+type CarCursor interface {
+	Next() bool
+	Car() models.Car
+	Err() error
+	Close() error
+}
+
 type CarStoreInterface interface {
 	GetCarById(ctx context.Context, id string) (models.Car, error)
-	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	// GetCarByVIN looks a live car up by its normalized VIN.
+	GetCarByVIN(ctx context.Context, vin string) (models.Car, error)
+	ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error)
+	ExportCars(ctx context.Context, filter models.CarFilter) (CarCursor, error)
 	CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error)
-	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error)
-	DeleteCar(ctx context.Context, id string) (models.Car, error)
+	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (models.Car, error)
+	PatchCar(ctx context.Context, id string, update models.CarUpdate, version int64) (models.Car, error)
+	DeleteCar(ctx context.Context, id string, version int64) (models.Car, error)
+	ListDeletedCars(ctx context.Context) ([]models.Car, error)
+	RestoreCar(ctx context.Context, id string) (models.Car, error)
+	PurgeCars(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetCarHistory(ctx context.Context, id string) ([]models.HistoryEntry, error)
+	GetCarPrices(ctx context.Context, id string) ([]models.PricePoint, error)
+	// CountCarsByFuelType counts the live cars of each fuel type.
+	CountCarsByFuelType(ctx context.Context) (map[string]int64, error)
 }
 
 type EngineStoreInterface interface {
 	GetEngineById(ctx context.Context, id string) (models.Engine, error)
+	ListEngines(ctx context.Context, filter models.EngineFilter) (models.EnginePage, error)
 	CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error)
-	UpdateEngine(ctx context.Context, id string, engine *models.EngineRequest) (models.Engine, error)
-	DeleteEngine(ctx context.Context, id string) (models.Engine, error)
+	UpdateEngine(ctx context.Context, id string, engine *models.EngineRequest, version int64) (models.Engine, error)
+	PatchEngine(ctx context.Context, id string, update models.EngineUpdate, version int64) (models.Engine, error)
+	DeleteEngine(ctx context.Context, id string, version int64, strategy string) (models.Engine, error)
+	GetEngineDependents(ctx context.Context, id string) ([]uuid.UUID, error)
+	ListDeletedEngines(ctx context.Context) ([]models.Engine, error)
+	RestoreEngine(ctx context.Context, id string) (models.Engine, error)
+	PurgeEngines(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetEngineHistory(ctx context.Context, id string) ([]models.HistoryEntry, error)
+}
+
+// ExchangeRateStoreInterface keeps the exchange rates used to convert car
+// prices. Changing a rate re-prices the cars in that currency against
+// models.BaseCurrency in the same transaction.
+type ExchangeRateStoreInterface interface {
+	ListRates(ctx context.Context) ([]models.ExchangeRate, error)
+	GetRate(ctx context.Context, currency string) (models.ExchangeRate, error)
+	SetRate(ctx context.Context, currency string, rate string) (models.ExchangeRate, error)
+}
+
+// IdempotencyStoreInterface keeps the responses of requests made with an
+// Idempotency-Key so that retries can be answered without redoing them.
+// Keys are identified by principal, scope and key together.
+type IdempotencyStoreInterface interface {
+	// Reserve claims the key for a new request. If the key is already taken
+	// and not expired, it returns the existing record and false instead. A
+	// key whose request did not complete within models.IdempotencyLease is
+	// expired.
+	Reserve(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error)
+	// Complete stores the response of a reserved key.
+	Complete(ctx context.Context, record models.IdempotencyRecord) error
+	// Release frees a reserved key so the request can be retried.
+	Release(ctx context.Context, principal, scope, key string) error
+}
+
+// APIKeyStoreInterface keeps the API keys issued to partners. Keys are
+// looked up by the hash of the key, which is all that is stored of it.
+type APIKeyStoreInterface interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error)
+	// RevokeAPIKey marks the key revoked. Revoking a revoked key keeps the
+	// time it was first revoked.
+	RevokeAPIKey(ctx context.Context, id string) (models.APIKey, error)
+}
+
+// TxManager runs a unit of work: every store call made with the context
+// passed to fn belongs to one transaction, committed if fn returns nil and
+// rolled back otherwise.
+type TxManager interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
 }
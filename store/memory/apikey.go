@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// APIKeyStore keeps API keys in memory. Like IdempotencyStore it has its
+// own lock, since keys are unrelated to the cars and engines in DB.
+type APIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]models.APIKey
+}
+
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		keys: map[uuid.UUID]models.APIKey{},
+	}
+}
+
+func (s *APIKeyStore) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key.Scopes = slices.Clone(key.Scopes)
+	s.keys[key.ID] = key
+	return key, nil
+}
+
+func (s *APIKeyStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID.String() < keys[j].ID.String()
+	})
+	return keys, nil
+}
+
+func (s *APIKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, models.NewNotFoundError("api key", "")
+}
+
+func (s *APIKeyStore) RevokeAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	keyID, err := uuid.Parse(id)
+	if err != nil {
+		return models.APIKey{}, models.NewInvalidIDError("api key", id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[keyID]
+	if !ok {
+		return models.APIKey{}, models.NewNotFoundError("api key", id)
+	}
+	if key.RevokedAt == nil {
+		revokedAt := time.Now()
+		key.RevokedAt = &revokedAt
+		s.keys[keyID] = key
+	}
+	return key, nil
+}
@@ -0,0 +1,511 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+type CarStore struct {
+	db *DB
+}
+
+func NewCarStore(db *DB) *CarStore {
+	return &CarStore{
+		db: db,
+	}
+}
+
+func (s *CarStore) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.NewInvalidIDError("car", id)
+	}
+	defer s.db.rlock(ctx)()
+
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, models.NewNotFoundError("car", id)
+	}
+	return s.db.withEngine(car), nil
+}
+
+func (s *CarStore) GetCarByVIN(ctx context.Context, vin string) (models.Car, error) {
+	defer s.db.rlock(ctx)()
+
+	for _, car := range s.db.cars {
+		if car.VIN == vin && car.DeletedAt == nil {
+			return s.db.withEngine(car), nil
+		}
+	}
+	return models.Car{}, models.NewNotFoundError("car with vin", vin)
+}
+
+func (s *CarStore) ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error) {
+	page := models.CarPage{Cars: []models.Car{}}
+	var cursor *models.Cursor
+	if filter.Cursor != "" {
+		decoded, err := models.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return page, err
+		}
+		cursor = &decoded
+	}
+
+	unlock := s.db.rlock(ctx)
+	var cars []models.Car
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			continue
+		}
+		car = s.db.withEngine(car)
+		if s.db.matchesFilter(car, filter) {
+			cars = append(cars, car)
+		}
+	}
+	unlock()
+
+	sort.Slice(cars, func(i, j int) bool {
+		return compareCars(cars[i], cars[j], filter.SortBy, filter.Descending) < 0
+	})
+
+	for _, car := range cars {
+		if cursor != nil && compareToCursor(car, *cursor, filter.Descending) <= 0 {
+			continue
+		}
+		if len(page.Cars) == filter.Limit {
+			last := page.Cars[len(page.Cars)-1]
+			page.NextCursor = models.EncodeCursor(models.Cursor{
+				SortBy:     filter.SortBy,
+				Descending: filter.Descending,
+				Value:      models.CarSortValue(last, filter.SortBy),
+				ID:         last.ID,
+			})
+			break
+		}
+		page.Cars = append(page.Cars, car)
+	}
+	return page, nil
+}
+
+// ExportCars snapshots the matching cars under the read lock, so the export
+// is consistent even if cars change while it is being written out.
+func (s *CarStore) ExportCars(ctx context.Context, filter models.CarFilter) (store.CarCursor, error) {
+	var cursor *models.Cursor
+	if filter.Cursor != "" {
+		decoded, err := models.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &decoded
+	}
+
+	unlock := s.db.rlock(ctx)
+	var cars []models.Car
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			continue
+		}
+		car = s.db.withEngine(car)
+		if s.db.matchesFilter(car, filter) && (cursor == nil || compareToCursor(car, *cursor, filter.Descending) > 0) {
+			cars = append(cars, car)
+		}
+	}
+	unlock()
+
+	sort.Slice(cars, func(i, j int) bool {
+		return compareCars(cars[i], cars[j], filter.SortBy, filter.Descending) < 0
+	})
+	return &carSlice{cars: cars, next: -1}, nil
+}
+
+// carSlice is a store.CarCursor over cars already in memory.
+type carSlice struct {
+	cars []models.Car
+	next int
+}
+
+func (c *carSlice) Next() bool {
+	if c.next+1 >= len(c.cars) {
+		return false
+	}
+	c.next++
+	return true
+}
+
+func (c *carSlice) Car() models.Car {
+	return c.cars[c.next]
+}
+
+func (c *carSlice) Err() error {
+	return nil
+}
+
+func (c *carSlice) Close() error {
+	return nil
+}
+
+func (db *DB) matchesFilter(car models.Car, filter models.CarFilter) bool {
+	if len(filter.Brands) > 0 && !slices.Contains(filter.Brands, car.Brand) {
+		return false
+	}
+	if len(filter.FuelTypes) > 0 && !slices.Contains(filter.FuelTypes, car.FuelType) {
+		return false
+	}
+	year, _ := strconv.Atoi(car.Year)
+	if filter.YearMin != 0 && year < filter.YearMin {
+		return false
+	}
+	if filter.YearMax != 0 && year > filter.YearMax {
+		return false
+	}
+	if filter.PriceMin != nil && car.BasePrice < filter.PriceMin.Amount {
+		return false
+	}
+	if filter.PriceMax != nil && car.BasePrice > filter.PriceMax.Amount {
+		return false
+	}
+	if filter.DisplacementMin != nil && car.Engine.Displacement < *filter.DisplacementMin {
+		return false
+	}
+	if filter.DisplacementMax != nil && car.Engine.Displacement > *filter.DisplacementMax {
+		return false
+	}
+	if filter.CylindersMin != nil && car.Engine.NoOfCylinders < *filter.CylindersMin {
+		return false
+	}
+	if filter.CylindersMax != nil && car.Engine.NoOfCylinders > *filter.CylindersMax {
+		return false
+	}
+	if filter.PriceDroppedSince != nil && !db.priceDroppedSince(car.ID, *filter.PriceDroppedSince) {
+		return false
+	}
+	return true
+}
+
+// compareCars orders cars by the sort key and then by id, matching the
+// ORDER BY of the SQL store.
+func compareCars(a, b models.Car, sortBy string, descending bool) int {
+	result := compareSortValues(sortBy, models.CarSortValue(a, sortBy), models.CarSortValue(b, sortBy))
+	if result == 0 {
+		result = strings.Compare(a.ID.String(), b.ID.String())
+	}
+	if descending {
+		return -result
+	}
+	return result
+}
+
+// compareToCursor reports whether the car sorts before (<0), at (0) or after
+// (>0) the cursor position.
+func compareToCursor(car models.Car, cursor models.Cursor, descending bool) int {
+	result := compareSortValues(cursor.SortBy, models.CarSortValue(car, cursor.SortBy), cursor.Value)
+	if result == 0 {
+		result = strings.Compare(car.ID.String(), cursor.ID.String())
+	}
+	if descending {
+		return -result
+	}
+	return result
+}
+
+func compareSortValues(sortBy string, a, b string) int {
+	switch sortBy {
+	case models.SortByPrice:
+		x, _ := strconv.ParseInt(a, 10, 64)
+		y, _ := strconv.ParseInt(b, 10, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case models.SortByCreatedAt:
+		x, _ := time.Parse(time.RFC3339Nano, a)
+		y, _ := time.Parse(time.RFC3339Nano, b)
+		return x.Compare(y)
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func (s *CarStore) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	defer s.db.lock(ctx)()
+
+	if _, ok := s.db.liveEngine(carReq.Engine.EngineID); !ok {
+		return models.Car{}, models.NewValidationError("engine.engine_id", models.CodeNotFound, "engine_id does not reference an existing engine")
+	}
+	if s.db.vinTaken(carReq.VIN, uuid.Nil) {
+		return models.Car{}, models.NewDuplicateError("car", "vin", carReq.VIN)
+	}
+	basePrice, err := s.db.basePrice(carReq.Price)
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	createdAt := time.Now()
+	car := models.Car{
+		ID:        uuid.New(),
+		Name:      carReq.Name,
+		Year:      carReq.Year,
+		Brand:     carReq.Brand,
+		FuelType:  carReq.FuelType,
+		VIN:       carReq.VIN,
+		Engine:    models.Engine{EngineID: carReq.Engine.EngineID},
+		Price:     carReq.Price,
+		BasePrice: basePrice,
+		Version:   1,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	if err := s.db.record(ctx, "car", car.ID, models.ActionCreate, nil, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[car.ID] = car
+	s.db.recordPrice(car, false)
+	return car, nil
+}
+
+func (s *CarStore) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (models.Car, error) {
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.NewInvalidIDError("car", id)
+	}
+	defer s.db.lock(ctx)()
+
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, models.NewNotFoundError("car", id)
+	}
+	if version != 0 && car.Version != version {
+		return models.Car{}, models.NewPreconditionFailedError("car", id)
+	}
+	before := car
+	if _, ok := s.db.liveEngine(carReq.Engine.EngineID); !ok {
+		return models.Car{}, models.NewValidationError("engine.engine_id", models.CodeNotFound, "engine_id does not reference an existing engine")
+	}
+	if s.db.vinTaken(carReq.VIN, carID) {
+		return models.Car{}, models.NewDuplicateError("car", "vin", carReq.VIN)
+	}
+	basePrice, err := s.db.basePrice(carReq.Price)
+	if err != nil {
+		return models.Car{}, err
+	}
+	car.Name = carReq.Name
+	car.Year = carReq.Year
+	car.Brand = carReq.Brand
+	car.FuelType = carReq.FuelType
+	car.VIN = carReq.VIN
+	car.Engine = models.Engine{EngineID: carReq.Engine.EngineID}
+	car.Price = carReq.Price
+	car.BasePrice = basePrice
+	car.Version++
+	car.UpdatedAt = time.Now()
+	if err := s.db.record(ctx, "car", carID, models.ActionUpdate, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[carID] = car
+	if car.Price != before.Price {
+		s.db.recordPrice(car, car.BasePrice < before.BasePrice)
+	}
+	return car, nil
+}
+
+func (s *CarStore) PatchCar(ctx context.Context, id string, update models.CarUpdate, version int64) (models.Car, error) {
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.NewInvalidIDError("car", id)
+	}
+	defer s.db.lock(ctx)()
+
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, models.NewNotFoundError("car", id)
+	}
+	if version != 0 && car.Version != version {
+		return models.Car{}, models.NewPreconditionFailedError("car", id)
+	}
+	before := car
+	if update.EngineID != nil {
+		if _, ok := s.db.liveEngine(*update.EngineID); !ok {
+			return models.Car{}, models.NewValidationError("engine.engine_id", models.CodeNotFound, "engine_id does not reference an existing engine")
+		}
+		car.Engine = models.Engine{EngineID: *update.EngineID}
+	}
+	if update.Name != nil {
+		car.Name = *update.Name
+	}
+	if update.Year != nil {
+		car.Year = *update.Year
+	}
+	if update.Brand != nil {
+		car.Brand = *update.Brand
+	}
+	if update.FuelType != nil {
+		car.FuelType = *update.FuelType
+	}
+	if update.VIN != nil {
+		if s.db.vinTaken(*update.VIN, carID) {
+			return models.Car{}, models.NewDuplicateError("car", "vin", *update.VIN)
+		}
+		car.VIN = *update.VIN
+	}
+	if update.Price != nil {
+		basePrice, err := s.db.basePrice(*update.Price)
+		if err != nil {
+			return models.Car{}, err
+		}
+		car.Price = *update.Price
+		car.BasePrice = basePrice
+	}
+	car.Version++
+	car.UpdatedAt = time.Now()
+	if err := s.db.record(ctx, "car", carID, models.ActionUpdate, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[carID] = car
+	if car.Price != before.Price {
+		s.db.recordPrice(car, car.BasePrice < before.BasePrice)
+	}
+	return car, nil
+}
+
+func (s *CarStore) DeleteCar(ctx context.Context, id string, version int64) (models.Car, error) {
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.NewInvalidIDError("car", id)
+	}
+	defer s.db.lock(ctx)()
+
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, models.NewNotFoundError("car", id)
+	}
+	if version != 0 && car.Version != version {
+		return models.Car{}, models.NewPreconditionFailedError("car", id)
+	}
+	before := car
+	deletedAt := time.Now()
+	car.DeletedAt = &deletedAt
+	car.UpdatedAt = deletedAt
+	car.Version++
+	if err := s.db.record(ctx, "car", carID, models.ActionDelete, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[carID] = car
+	return car, nil
+}
+
+func (s *CarStore) ListDeletedCars(ctx context.Context) ([]models.Car, error) {
+	defer s.db.rlock(ctx)()
+
+	cars := []models.Car{}
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			cars = append(cars, s.db.withEngine(car))
+		}
+	}
+	sort.Slice(cars, func(i, j int) bool {
+		if !cars[i].DeletedAt.Equal(*cars[j].DeletedAt) {
+			return cars[i].DeletedAt.After(*cars[j].DeletedAt)
+		}
+		return cars[i].ID.String() < cars[j].ID.String()
+	})
+	return cars, nil
+}
+
+func (s *CarStore) RestoreCar(ctx context.Context, id string) (models.Car, error) {
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, models.NewInvalidIDError("car", id)
+	}
+	defer s.db.lock(ctx)()
+
+	car, ok := s.db.cars[carID]
+	if !ok || car.DeletedAt == nil {
+		return models.Car{}, models.NewNotFoundError("deleted car", id)
+	}
+	if _, ok := s.db.liveEngine(car.Engine.EngineID); !ok && car.Engine.EngineID != uuid.Nil {
+		return models.Car{}, models.NewConflictError("the car's engine is deleted, restore the engine first")
+	}
+	if s.db.vinTaken(car.VIN, carID) {
+		return models.Car{}, models.NewDuplicateError("car", "vin", car.VIN)
+	}
+	before := car
+	car.DeletedAt = nil
+	car.UpdatedAt = time.Now()
+	car.Version++
+	if err := s.db.record(ctx, "car", carID, models.ActionRestore, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[carID] = car
+	return car, nil
+}
+
+func (s *CarStore) PurgeCars(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	defer s.db.lock(ctx)()
+
+	var purged int64
+	for id, car := range s.db.cars {
+		if car.DeletedAt != nil && car.DeletedAt.Before(deletedBefore) {
+			if err := s.db.record(ctx, "car", id, models.ActionPurge, car, nil); err != nil {
+				return purged, err
+			}
+			delete(s.db.cars, id)
+			delete(s.db.prices, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *CarStore) GetCarHistory(ctx context.Context, id string) ([]models.HistoryEntry, error) {
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, models.NewInvalidIDError("car", id)
+	}
+	defer s.db.rlock(ctx)()
+
+	entries := s.db.historyOf("car", carID)
+	if len(entries) == 0 {
+		return nil, models.NewNotFoundError("car", id)
+	}
+	return entries, nil
+}
+
+func (s *CarStore) GetCarPrices(ctx context.Context, id string) ([]models.PricePoint, error) {
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, models.NewInvalidIDError("car", id)
+	}
+	defer s.db.rlock(ctx)()
+
+	if _, ok := s.db.liveCar(carID); !ok {
+		return nil, models.NewNotFoundError("car", id)
+	}
+	points := s.db.prices[carID]
+	if len(points) == 0 {
+		return nil, models.NewNotFoundError("car", id)
+	}
+	return slices.Clone(points), nil
+}
+
+func (s *CarStore) CountCarsByFuelType(ctx context.Context) (map[string]int64, error) {
+	defer s.db.rlock(ctx)()
+
+	counts := map[string]int64{}
+	for _, car := range s.db.cars {
+		if car.DeletedAt == nil {
+			counts[car.FuelType]++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,331 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+type EngineStore struct {
+	db *DB
+}
+
+func NewEngineStore(db *DB) *EngineStore {
+	return &EngineStore{
+		db: db,
+	}
+}
+
+func (e *EngineStore) GetEngineById(ctx context.Context, id string) (models.Engine, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, models.NewInvalidIDError("engine", id)
+	}
+	defer e.db.rlock(ctx)()
+
+	engine, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, models.NewNotFoundError("engine", id)
+	}
+	return engine, nil
+}
+
+func (e *EngineStore) ListEngines(ctx context.Context, filter models.EngineFilter) (models.EnginePage, error) {
+	page := models.EnginePage{Engines: []models.EngineSummary{}}
+	var cursor *models.Cursor
+	if filter.Cursor != "" {
+		decoded, err := models.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return page, err
+		}
+		cursor = &decoded
+	}
+
+	unlock := e.db.rlock(ctx)
+	carCounts := map[uuid.UUID]int64{}
+	for _, car := range e.db.cars {
+		if car.DeletedAt == nil {
+			carCounts[car.Engine.EngineID]++
+		}
+	}
+	var engines []models.EngineSummary
+	for _, engine := range e.db.engines {
+		if engine.DeletedAt == nil && matchesEngineFilter(engine, filter) {
+			engines = append(engines, models.EngineSummary{Engine: engine, CarCount: carCounts[engine.EngineID]})
+		}
+	}
+	unlock()
+
+	// compare reports the order of an engine relative to a (sort value, id)
+	// position, honouring the requested direction
+	compare := func(engine models.Engine, value int64, id uuid.UUID) int {
+		own, _ := strconv.ParseInt(models.EngineSortValue(engine, filter.SortBy), 10, 64)
+		result := cmp.Compare(own, value)
+		if result == 0 {
+			result = strings.Compare(engine.EngineID.String(), id.String())
+		}
+		if filter.Descending {
+			return -result
+		}
+		return result
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		other := engines[j].Engine
+		value, _ := strconv.ParseInt(models.EngineSortValue(other, filter.SortBy), 10, 64)
+		return compare(engines[i].Engine, value, other.EngineID) < 0
+	})
+
+	for _, engine := range engines {
+		if cursor != nil {
+			value, _ := strconv.ParseInt(cursor.Value, 10, 64)
+			if compare(engine.Engine, value, cursor.ID) <= 0 {
+				continue
+			}
+		}
+		if len(page.Engines) == filter.Limit {
+			last := page.Engines[len(page.Engines)-1]
+			page.NextCursor = models.EncodeCursor(models.Cursor{
+				SortBy:     filter.SortBy,
+				Descending: filter.Descending,
+				Value:      models.EngineSortValue(last.Engine, filter.SortBy),
+				ID:         last.EngineID,
+			})
+			break
+		}
+		page.Engines = append(page.Engines, engine)
+	}
+	return page, nil
+}
+
+func matchesEngineFilter(engine models.Engine, filter models.EngineFilter) bool {
+	inRange := func(value int64, min, max *int64) bool {
+		return (min == nil || value >= *min) && (max == nil || value <= *max)
+	}
+	return inRange(engine.Displacement, filter.DisplacementMin, filter.DisplacementMax) &&
+		inRange(engine.NoOfCylinders, filter.CylindersMin, filter.CylindersMax) &&
+		inRange(engine.CarRange, filter.RangeMin, filter.RangeMax)
+}
+
+func (e *EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	defer e.db.lock(ctx)()
+
+	engine := models.Engine{
+		EngineID:      uuid.New(),
+		Displacement:  engineReq.Displacement,
+		NoOfCylinders: engineReq.NoOfCylinders,
+		CarRange:      engineReq.CarRange,
+		Version:       1,
+	}
+	if err := e.db.record(ctx, "engine", engine.EngineID, models.ActionCreate, nil, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engine.EngineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (models.Engine, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, models.NewInvalidIDError("engine", id)
+	}
+	defer e.db.lock(ctx)()
+
+	current, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, models.NewNotFoundError("engine", id)
+	}
+	if version != 0 && current.Version != version {
+		return models.Engine{}, models.NewPreconditionFailedError("engine", id)
+	}
+	engine := models.Engine{
+		EngineID:      engineID,
+		Displacement:  engineReq.Displacement,
+		NoOfCylinders: engineReq.NoOfCylinders,
+		CarRange:      engineReq.CarRange,
+		Version:       current.Version + 1,
+	}
+	if err := e.db.record(ctx, "engine", engineID, models.ActionUpdate, current, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) PatchEngine(ctx context.Context, id string, update models.EngineUpdate, version int64) (models.Engine, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, models.NewInvalidIDError("engine", id)
+	}
+	defer e.db.lock(ctx)()
+
+	engine, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, models.NewNotFoundError("engine", id)
+	}
+	if version != 0 && engine.Version != version {
+		return models.Engine{}, models.NewPreconditionFailedError("engine", id)
+	}
+	before := engine
+	if update.Displacement != nil {
+		engine.Displacement = *update.Displacement
+	}
+	if update.NoOfCylinders != nil {
+		engine.NoOfCylinders = *update.NoOfCylinders
+	}
+	if update.CarRange != nil {
+		engine.CarRange = *update.CarRange
+	}
+	engine.Version++
+	if err := e.db.record(ctx, "engine", engineID, models.ActionUpdate, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) DeleteEngine(ctx context.Context, id string, version int64, strategy string) (models.Engine, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, models.NewInvalidIDError("engine", id)
+	}
+	defer e.db.lock(ctx)()
+
+	engine, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, models.NewNotFoundError("engine", id)
+	}
+	if version != 0 && engine.Version != version {
+		return models.Engine{}, models.NewPreconditionFailedError("engine", id)
+	}
+	before := engine
+	deletedAt := time.Now()
+	dependents := e.db.dependentCars(engineID)
+	if len(dependents) > 0 && strategy != models.DeleteCascade && strategy != models.DeleteDetach {
+		carIDs := make([]uuid.UUID, len(dependents))
+		for i, car := range dependents {
+			carIDs[i] = car.ID
+		}
+		return models.Engine{}, models.NewDependentsError("engine", id, carIDs)
+	}
+	for _, car := range dependents {
+		carBefore := car
+		car.UpdatedAt = deletedAt
+		car.Version++
+		action := models.ActionUpdate
+		if strategy == models.DeleteCascade {
+			car.DeletedAt = &deletedAt
+			action = models.ActionDelete
+		} else {
+			car.Engine = models.Engine{}
+		}
+		if err := e.db.record(ctx, "car", car.ID, action, carBefore, car); err != nil {
+			return models.Engine{}, err
+		}
+		e.db.cars[car.ID] = car
+	}
+	engine.DeletedAt = &deletedAt
+	engine.Version++
+	if err := e.db.record(ctx, "engine", engineID, models.ActionDelete, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) GetEngineDependents(ctx context.Context, id string) ([]uuid.UUID, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, models.NewInvalidIDError("engine", id)
+	}
+	defer e.db.rlock(ctx)()
+
+	if _, ok := e.db.liveEngine(engineID); !ok {
+		return nil, models.NewNotFoundError("engine", id)
+	}
+	carIDs := []uuid.UUID{}
+	for _, car := range e.db.dependentCars(engineID) {
+		carIDs = append(carIDs, car.ID)
+	}
+	return carIDs, nil
+}
+
+func (e *EngineStore) ListDeletedEngines(ctx context.Context) ([]models.Engine, error) {
+	defer e.db.rlock(ctx)()
+
+	engines := []models.Engine{}
+	for _, engine := range e.db.engines {
+		if engine.DeletedAt != nil {
+			engines = append(engines, engine)
+		}
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		if !engines[i].DeletedAt.Equal(*engines[j].DeletedAt) {
+			return engines[i].DeletedAt.After(*engines[j].DeletedAt)
+		}
+		return engines[i].EngineID.String() < engines[j].EngineID.String()
+	})
+	return engines, nil
+}
+
+func (e *EngineStore) RestoreEngine(ctx context.Context, id string) (models.Engine, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, models.NewInvalidIDError("engine", id)
+	}
+	defer e.db.lock(ctx)()
+
+	engine, ok := e.db.engines[engineID]
+	if !ok || engine.DeletedAt == nil {
+		return models.Engine{}, models.NewNotFoundError("deleted engine", id)
+	}
+	before := engine
+	engine.DeletedAt = nil
+	engine.Version++
+	if err := e.db.record(ctx, "engine", engineID, models.ActionRestore, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+// PurgeEngines keeps engines still referenced by a car, like the foreign
+// key does in the SQL store.
+func (e *EngineStore) PurgeEngines(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	defer e.db.lock(ctx)()
+
+	referenced := map[uuid.UUID]bool{}
+	for _, car := range e.db.cars {
+		referenced[car.Engine.EngineID] = true
+	}
+	var purged int64
+	for id, engine := range e.db.engines {
+		if engine.DeletedAt != nil && engine.DeletedAt.Before(deletedBefore) && !referenced[id] {
+			if err := e.db.record(ctx, "engine", id, models.ActionPurge, engine, nil); err != nil {
+				return purged, err
+			}
+			delete(e.db.engines, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (e *EngineStore) GetEngineHistory(ctx context.Context, id string) ([]models.HistoryEntry, error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, models.NewInvalidIDError("engine", id)
+	}
+	defer e.db.rlock(ctx)()
+
+	entries := e.db.historyOf("engine", engineID)
+	if len(entries) == 0 {
+		return nil, models.NewNotFoundError("engine", id)
+	}
+	return entries, nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+type ExchangeRateStore struct {
+	db *DB
+}
+
+func NewExchangeRateStore(db *DB) *ExchangeRateStore {
+	return &ExchangeRateStore{
+		db: db,
+	}
+}
+
+func (s *ExchangeRateStore) ListRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	defer s.db.rlock(ctx)()
+
+	rates := []models.ExchangeRate{}
+	for _, rate := range s.db.rates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Currency < rates[j].Currency
+	})
+	return rates, nil
+}
+
+func (s *ExchangeRateStore) GetRate(ctx context.Context, currency string) (models.ExchangeRate, error) {
+	defer s.db.rlock(ctx)()
+
+	rate, ok := s.db.rates[currency]
+	if !ok {
+		return models.ExchangeRate{}, models.NewNotFoundError("exchange rate", currency)
+	}
+	return rate, nil
+}
+
+// SetRate creates or replaces the rate of a currency and recomputes the
+// base price of every car priced in it, including those in the trash.
+func (s *ExchangeRateStore) SetRate(ctx context.Context, currency string, rate string) (models.ExchangeRate, error) {
+	defer s.db.lock(ctx)()
+
+	saved := models.ExchangeRate{Currency: currency, UpdatedAt: time.Now()}
+	saved.Rate = models.FormatRate(models.ExchangeRate{Rate: rate}.Rat())
+	s.db.rates[currency] = saved
+	for id, car := range s.db.cars {
+		if car.Price.Currency == currency {
+			car.BasePrice = car.Price.BaseAmount(saved.Rat())
+			s.db.cars[id] = car
+		}
+	}
+	return saved, nil
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// IdempotencyStore keeps idempotency keys in memory. It has its own lock
+// since keys are unrelated to the cars and engines in DB.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	records map[[3]string]models.IdempotencyRecord
+}
+
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{
+		records: map[[3]string]models.IdempotencyRecord{},
+	}
+}
+
+func (s *IdempotencyStore) Reserve(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [3]string{record.Principal, record.Scope, record.Key}
+	now := time.Now()
+	if existing, ok := s.records[id]; ok && !existing.Expired(now) {
+		return existing, false, nil
+	}
+	record.Status = 0
+	record.ContentType = ""
+	record.Body = nil
+	record.CreatedAt = now
+	s.records[id] = record
+	return record, true, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [3]string{record.Principal, record.Scope, record.Key}
+	if existing, ok := s.records[id]; ok {
+		existing.Status = record.Status
+		existing.ContentType = record.ContentType
+		existing.Body = record.Body
+		s.records[id] = existing
+	}
+	return nil
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, principal, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [3]string{principal, scope, key}
+	if existing, ok := s.records[id]; ok && !existing.Completed() {
+		delete(s.records, id)
+	}
+	return nil
+}
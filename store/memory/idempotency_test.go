@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestIdempotencyLease(t *testing.T) {
+	ctx := context.Background()
+	s := NewIdempotencyStore()
+	record := models.IdempotencyRecord{Principal: "user:alice", Scope: "POST /cars", Key: "k1", RequestHash: "a"}
+	id := [3]string{record.Principal, record.Scope, record.Key}
+	backdate := func(age time.Duration) {
+		existing := s.records[id]
+		existing.CreatedAt = time.Now().Add(-age)
+		s.records[id] = existing
+	}
+
+	if _, reserved, _ := s.Reserve(ctx, record); !reserved {
+		t.Fatal("a new key was not reserved")
+	}
+	if _, reserved, _ := s.Reserve(ctx, record); reserved {
+		t.Fatal("a key held by a running request was taken over")
+	}
+	backdate(models.IdempotencyLease + time.Second)
+	if _, reserved, _ := s.Reserve(ctx, record); !reserved {
+		t.Fatal("a key whose request outlived its lease was not taken over")
+	}
+
+	record.Status = 201
+	if err := s.Complete(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	backdate(models.IdempotencyLease + time.Second)
+	if _, reserved, _ := s.Reserve(ctx, record); reserved {
+		t.Fatal("a completed key was taken over once the lease ran out")
+	}
+	backdate(models.IdempotencyKeyTTL + time.Second)
+	if _, reserved, _ := s.Reserve(ctx, record); !reserved {
+		t.Fatal("an expired key was not taken over")
+	}
+}
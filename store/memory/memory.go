@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/audit"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// DB is the shared state behind CarStore and EngineStore. Both stores must
+// be built on the same DB so cars can be joined with their engines.
+type DB struct {
+	mu      sync.RWMutex
+	cars    map[uuid.UUID]models.Car
+	engines map[uuid.UUID]models.Engine
+	history []models.HistoryEntry
+	rates   map[string]models.ExchangeRate
+	prices  map[uuid.UUID][]models.PricePoint
+}
+
+// NewDB returns an empty DB that only knows the rate of the base currency.
+func NewDB() *DB {
+	return &DB{
+		cars:    map[uuid.UUID]models.Car{},
+		engines: map[uuid.UUID]models.Engine{},
+		prices:  map[uuid.UUID][]models.PricePoint{},
+		rates: map[string]models.ExchangeRate{
+			models.BaseCurrency: {Currency: models.BaseCurrency, Rate: "1", UpdatedAt: time.Now()},
+		},
+	}
+}
+
+// withEngine returns the car with its engine data filled in, the same way
+// the SQL store LEFT JOINs the engine table.
+func (db *DB) withEngine(car models.Car) models.Car {
+	if engine, ok := db.engines[car.Engine.EngineID]; ok {
+		car.Engine = engine
+	}
+	return car
+}
+
+// liveCar returns the car unless it does not exist or is in the trash.
+func (db *DB) liveCar(id uuid.UUID) (models.Car, bool) {
+	car, ok := db.cars[id]
+	if !ok || car.DeletedAt != nil {
+		return models.Car{}, false
+	}
+	return car, true
+}
+
+// liveEngine returns the engine unless it does not exist or is in the trash.
+func (db *DB) liveEngine(id uuid.UUID) (models.Engine, bool) {
+	engine, ok := db.engines[id]
+	if !ok || engine.DeletedAt != nil {
+		return models.Engine{}, false
+	}
+	return engine, true
+}
+
+// vinTaken reports whether a live car other than the given one has the
+// VIN. Unknown VINs never clash.
+func (db *DB) vinTaken(vin string, except uuid.UUID) bool {
+	if vin == "" {
+		return false
+	}
+	for id, car := range db.cars {
+		if id != except && car.VIN == vin && car.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// basePrice converts a price to the base currency at the current rate of
+// its currency.
+func (db *DB) basePrice(price models.Money) (int64, error) {
+	rate, ok := db.rates[price.Currency]
+	if !ok {
+		return 0, models.NewValidationError("price.currency", models.CodeNotFound, "no exchange rate is set for "+price.Currency)
+	}
+	return price.BaseAmount(rate.Rat()), nil
+}
+
+// dependentCars returns the live cars using the engine, ordered by id like
+// the SQL store.
+func (db *DB) dependentCars(engineID uuid.UUID) []models.Car {
+	var cars []models.Car
+	for _, car := range db.cars {
+		if car.Engine.EngineID == engineID && car.DeletedAt == nil {
+			cars = append(cars, car)
+		}
+	}
+	sort.Slice(cars, func(i, j int) bool {
+		return cars[i].ID.String() < cars[j].ID.String()
+	})
+	return cars
+}
+
+// record appends a history entry. The caller must hold the write lock and
+// call it before changing the maps, so a failure leaves nothing half done.
+func (db *DB) record(ctx context.Context, entityType string, entityID uuid.UUID, action string, before, after any) error {
+	entry, err := audit.NewEntry(ctx, entityType, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+	entry.ID = int64(len(db.history) + 1)
+	db.history = append(db.history, entry)
+	return nil
+}
+
+// recordPrice adds the car's current price to its price history.
+func (db *DB) recordPrice(car models.Car, dropped bool) {
+	db.prices[car.ID] = append(db.prices[car.ID], models.PricePoint{
+		Price:     car.Price,
+		Dropped:   dropped,
+		ChangedAt: car.UpdatedAt,
+	})
+}
+
+// priceDroppedSince reports whether the car's price was lowered at or after
+// the given time.
+func (db *DB) priceDroppedSince(carID uuid.UUID, since time.Time) bool {
+	for _, point := range db.prices[carID] {
+		if point.Dropped && !point.ChangedAt.Before(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// historyOf returns the entries of an entity, oldest first.
+func (db *DB) historyOf(entityType string, entityID uuid.UUID) []models.HistoryEntry {
+	entries := []models.HistoryEntry{}
+	for _, entry := range db.history {
+		if entry.EntityType == entityType && entry.EntityID == entityID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+// Seed fills the DB with a small catalog so the API is usable without
+// Postgres, e.g. for frontend development.
+func Seed(db *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	engines := []models.Engine{
+		{EngineID: uuid.MustParse("e1f86b1a-0873-4c19-bae2-fc60329d0140"), Displacement: 2000, NoOfCylinders: 4, CarRange: 600},
+		{EngineID: uuid.MustParse("f4a9c66b-8e38-419b-93c4-215d5cefb318"), Displacement: 1600, NoOfCylinders: 4, CarRange: 550},
+		{EngineID: uuid.MustParse("cc2c2a7d-2e21-4f59-b7b8-bd9e5e4cf04c"), Displacement: 3000, NoOfCylinders: 6, CarRange: 700},
+		{EngineID: uuid.MustParse("9746be12-07b7-42a3-b8ab-7d1f209b63d7"), Displacement: 1800, NoOfCylinders: 4, CarRange: 500},
+	}
+	for _, engine := range engines {
+		engine.Version = 1
+		db.engines[engine.EngineID] = engine
+	}
+
+	now := time.Now()
+	for currency, rate := range map[string]string{"EUR": "0.92", "GBP": "0.79", "INR": "83.2", "JPY": "151.4"} {
+		db.rates[currency] = models.ExchangeRate{Currency: currency, Rate: rate, UpdatedAt: now}
+	}
+
+	cars := []models.Car{
+		{ID: uuid.MustParse("c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3"), Name: "Honda Civic", VIN: "2HGFE2F53PH500123", Year: "2023", Brand: "Honda", FuelType: "Petrol", Engine: models.Engine{EngineID: engines[0].EngineID}, Price: models.Money{Amount: 2500000, Currency: "USD"}},
+		{ID: uuid.MustParse("9d6a56f8-79c3-4931-a5c0-6b290c84ba2f"), Name: "Toyota Corolla", VIN: "JTDBDMHE1N3012345", Year: "2022", Brand: "Toyota", FuelType: "Hybrid", Engine: models.Engine{EngineID: engines[1].EngineID}, Price: models.Money{Amount: 2200000, Currency: "USD"}},
+		{ID: uuid.MustParse("9b9437c4-3ed1-45a5-b240-0fe3e24e0e4e"), Name: "Ford Mustang", VIN: "1FA6P8TH8R5101234", Year: "2024", Brand: "Ford", FuelType: "Petrol", Engine: models.Engine{EngineID: engines[2].EngineID}, Price: models.Money{Amount: 4000000, Currency: "USD"}},
+		{ID: uuid.MustParse("5e9df51a-8d7a-4d84-9c58-4ccfe5c7db06"), Name: "BMW 3 Series", VIN: "WBA5R1C04PFN12345", Year: "2023", Brand: "BMW", FuelType: "Diesel", Engine: models.Engine{EngineID: engines[3].EngineID}, Price: models.Money{Amount: 3200000, Currency: "EUR"}},
+	}
+	for _, car := range cars {
+		car.Version = 1
+		car.BasePrice, _ = db.basePrice(car.Price)
+		car.CreatedAt = now
+		car.UpdatedAt = now
+		db.cars[car.ID] = car
+		db.recordPrice(car, false)
+	}
+}
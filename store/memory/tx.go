@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"maps"
+)
+
+// unitKey marks a context running inside a unit of work on a DB. The unit
+// holds the DB's write lock for its whole run, so the stores must not lock
+// again for calls made within it.
+type unitKey struct{}
+
+func (db *DB) inUnit(ctx context.Context) bool {
+	return ctx.Value(unitKey{}) == db
+}
+
+// lock takes the write lock unless the context's unit already holds it, and
+// returns the matching unlock.
+func (db *DB) lock(ctx context.Context) func() {
+	if db.inUnit(ctx) {
+		return func() {}
+	}
+	db.mu.Lock()
+	return db.mu.Unlock
+}
+
+// rlock is lock for reads.
+func (db *DB) rlock(ctx context.Context) func() {
+	if db.inUnit(ctx) {
+		return func() {}
+	}
+	db.mu.RLock()
+	return db.mu.RUnlock
+}
+
+// TxManager runs units of work on a DB, the in-memory counterpart of
+// uow.Manager. Units run one at a time and see no concurrent changes; a
+// failed unit puts back the cars, engines, rates, prices and history it
+// started with.
+type TxManager struct {
+	db *DB
+}
+
+func NewTxManager(db *DB) *TxManager {
+	return &TxManager{
+		db: db,
+	}
+}
+
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.db.inUnit(ctx) {
+		return fn(ctx)
+	}
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	cars := maps.Clone(m.db.cars)
+	engines := maps.Clone(m.db.engines)
+	rates := maps.Clone(m.db.rates)
+	prices := maps.Clone(m.db.prices)
+	historyLen := len(m.db.history)
+	restore := func() {
+		m.db.cars = cars
+		m.db.engines = engines
+		m.db.rates = rates
+		m.db.prices = prices
+		m.db.history = m.db.history[:historyLen]
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			restore()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, unitKey{}, m.db)); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Package metered wraps stores to time each of their methods and count
+// the calls that failed.
+package metered
+
+import (
+	"context"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/metrics"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+type CarStore struct {
+	next    store.CarStoreInterface
+	metrics *metrics.StoreMetrics
+}
+
+func NewCarStore(next store.CarStoreInterface, m *metrics.StoreMetrics) *CarStore {
+	return &CarStore{
+		next:    next,
+		metrics: m,
+	}
+}
+
+func (s *CarStore) observe(method string, start time.Time, err *error) {
+	s.metrics.Observe("car", method, start, *err)
+}
+
+func (s *CarStore) GetCarById(ctx context.Context, id string) (result models.Car, err error) {
+	defer s.observe("GetCarById", time.Now(), &err)
+	return s.next.GetCarById(ctx, id)
+}
+
+func (s *CarStore) GetCarByVIN(ctx context.Context, vin string) (result models.Car, err error) {
+	defer s.observe("GetCarByVIN", time.Now(), &err)
+	return s.next.GetCarByVIN(ctx, vin)
+}
+
+func (s *CarStore) ListCars(ctx context.Context, filter models.CarFilter) (result models.CarPage, err error) {
+	defer s.observe("ListCars", time.Now(), &err)
+	return s.next.ListCars(ctx, filter)
+}
+
+func (s *CarStore) ExportCars(ctx context.Context, filter models.CarFilter) (result store.CarCursor, err error) {
+	defer s.observe("ExportCars", time.Now(), &err)
+	return s.next.ExportCars(ctx, filter)
+}
+
+func (s *CarStore) CreateCar(ctx context.Context, carReq *models.CarRequest) (result models.Car, err error) {
+	defer s.observe("CreateCar", time.Now(), &err)
+	return s.next.CreateCar(ctx, carReq)
+}
+
+func (s *CarStore) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (result models.Car, err error) {
+	defer s.observe("UpdateCar", time.Now(), &err)
+	return s.next.UpdateCar(ctx, id, carReq, version)
+}
+
+func (s *CarStore) PatchCar(ctx context.Context, id string, update models.CarUpdate, version int64) (result models.Car, err error) {
+	defer s.observe("PatchCar", time.Now(), &err)
+	return s.next.PatchCar(ctx, id, update, version)
+}
+
+func (s *CarStore) DeleteCar(ctx context.Context, id string, version int64) (result models.Car, err error) {
+	defer s.observe("DeleteCar", time.Now(), &err)
+	return s.next.DeleteCar(ctx, id, version)
+}
+
+func (s *CarStore) ListDeletedCars(ctx context.Context) (result []models.Car, err error) {
+	defer s.observe("ListDeletedCars", time.Now(), &err)
+	return s.next.ListDeletedCars(ctx)
+}
+
+func (s *CarStore) RestoreCar(ctx context.Context, id string) (result models.Car, err error) {
+	defer s.observe("RestoreCar", time.Now(), &err)
+	return s.next.RestoreCar(ctx, id)
+}
+
+func (s *CarStore) PurgeCars(ctx context.Context, deletedBefore time.Time) (result int64, err error) {
+	defer s.observe("PurgeCars", time.Now(), &err)
+	return s.next.PurgeCars(ctx, deletedBefore)
+}
+
+func (s *CarStore) GetCarHistory(ctx context.Context, id string) (result []models.HistoryEntry, err error) {
+	defer s.observe("GetCarHistory", time.Now(), &err)
+	return s.next.GetCarHistory(ctx, id)
+}
+
+func (s *CarStore) GetCarPrices(ctx context.Context, id string) (result []models.PricePoint, err error) {
+	defer s.observe("GetCarPrices", time.Now(), &err)
+	return s.next.GetCarPrices(ctx, id)
+}
+
+func (s *CarStore) CountCarsByFuelType(ctx context.Context) (result map[string]int64, err error) {
+	defer s.observe("CountCarsByFuelType", time.Now(), &err)
+	return s.next.CountCarsByFuelType(ctx)
+}
@@ -0,0 +1,82 @@
+package metered
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/metrics"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+)
+
+type EngineStore struct {
+	next    store.EngineStoreInterface
+	metrics *metrics.StoreMetrics
+}
+
+func NewEngineStore(next store.EngineStoreInterface, m *metrics.StoreMetrics) *EngineStore {
+	return &EngineStore{
+		next:    next,
+		metrics: m,
+	}
+}
+
+func (s *EngineStore) observe(method string, start time.Time, err *error) {
+	s.metrics.Observe("engine", method, start, *err)
+}
+
+func (s *EngineStore) GetEngineById(ctx context.Context, id string) (result models.Engine, err error) {
+	defer s.observe("GetEngineById", time.Now(), &err)
+	return s.next.GetEngineById(ctx, id)
+}
+
+func (s *EngineStore) ListEngines(ctx context.Context, filter models.EngineFilter) (result models.EnginePage, err error) {
+	defer s.observe("ListEngines", time.Now(), &err)
+	return s.next.ListEngines(ctx, filter)
+}
+
+func (s *EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (result models.Engine, err error) {
+	defer s.observe("CreateEngine", time.Now(), &err)
+	return s.next.CreateEngine(ctx, engineReq)
+}
+
+func (s *EngineStore) UpdateEngine(ctx context.Context, id string, engine *models.EngineRequest, version int64) (result models.Engine, err error) {
+	defer s.observe("UpdateEngine", time.Now(), &err)
+	return s.next.UpdateEngine(ctx, id, engine, version)
+}
+
+func (s *EngineStore) PatchEngine(ctx context.Context, id string, update models.EngineUpdate, version int64) (result models.Engine, err error) {
+	defer s.observe("PatchEngine", time.Now(), &err)
+	return s.next.PatchEngine(ctx, id, update, version)
+}
+
+func (s *EngineStore) DeleteEngine(ctx context.Context, id string, version int64, strategy string) (result models.Engine, err error) {
+	defer s.observe("DeleteEngine", time.Now(), &err)
+	return s.next.DeleteEngine(ctx, id, version, strategy)
+}
+
+func (s *EngineStore) GetEngineDependents(ctx context.Context, id string) (result []uuid.UUID, err error) {
+	defer s.observe("GetEngineDependents", time.Now(), &err)
+	return s.next.GetEngineDependents(ctx, id)
+}
+
+func (s *EngineStore) ListDeletedEngines(ctx context.Context) (result []models.Engine, err error) {
+	defer s.observe("ListDeletedEngines", time.Now(), &err)
+	return s.next.ListDeletedEngines(ctx)
+}
+
+func (s *EngineStore) RestoreEngine(ctx context.Context, id string) (result models.Engine, err error) {
+	defer s.observe("RestoreEngine", time.Now(), &err)
+	return s.next.RestoreEngine(ctx, id)
+}
+
+func (s *EngineStore) PurgeEngines(ctx context.Context, deletedBefore time.Time) (result int64, err error) {
+	defer s.observe("PurgeEngines", time.Now(), &err)
+	return s.next.PurgeEngines(ctx, deletedBefore)
+}
+
+func (s *EngineStore) GetEngineHistory(ctx context.Context, id string) (result []models.HistoryEntry, err error) {
+	defer s.observe("GetEngineHistory", time.Now(), &err)
+	return s.next.GetEngineHistory(ctx, id)
+}
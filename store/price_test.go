@@ -0,0 +1,60 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestCarPrices(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		car := createCar(t, s, engine.EngineID)
+		id := car.ID.String()
+		cheaper := carRequest(engine.EngineID)
+		cheaper.Price.Amount = 2400000
+		if _, err := s.cars.UpdateCar(ctx, id, cheaper, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		points, err := s.cars.GetCarPrices(ctx, id)
+		if err != nil {
+			t.Fatalf("GetCarPrices: %v", err)
+		}
+		if len(points) != 2 || points[0].Price.Amount != 2500000 || points[1].Price.Amount != 2400000 || !points[1].Dropped {
+			t.Fatalf("got price history %+v, want 25000.00 then a drop to 24000.00", points)
+		}
+
+		if _, err := s.cars.DeleteCar(ctx, id, 0); err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.cars.GetCarPrices(ctx, id)
+		wantErr(t, err, models.ErrNotFound)
+		if _, err := s.cars.RestoreCar(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if points, err := s.cars.GetCarPrices(ctx, id); err != nil || len(points) != 2 {
+			t.Fatalf("the restored car has price history %v, %v, want both prices back", points, err)
+		}
+	})
+}
+
+func TestWritesToMissingCarsAreNotFound(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		missing := uuid.NewString()
+		unknownEngine := uuid.New()
+
+		req := carRequest(unknownEngine)
+		req.Price.Currency = "EUR"
+		_, err := s.cars.UpdateCar(ctx, missing, req, 0)
+		wantErr(t, err, models.ErrNotFound)
+		_, err = s.cars.PatchCar(ctx, missing, models.CarUpdate{EngineID: &unknownEngine, Price: &req.Price}, 0)
+		wantErr(t, err, models.ErrNotFound)
+		_, err = s.cars.DeleteCar(ctx, missing, 0)
+		wantErr(t, err, models.ErrNotFound)
+	})
+}
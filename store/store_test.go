@@ -0,0 +1,128 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/internal/testdb"
+	"github.com/pranayyb/DriveThrough/migrations"
+	"github.com/pranayyb/DriveThrough/models"
+	"github.com/pranayyb/DriveThrough/store"
+	carStore "github.com/pranayyb/DriveThrough/store/car"
+	engineStore "github.com/pranayyb/DriveThrough/store/engine"
+	"github.com/pranayyb/DriveThrough/store/memory"
+	"github.com/pranayyb/DriveThrough/store/uow"
+)
+
+// stores is one backend's implementation of the store interfaces. The tests
+// in this package run against every backend, so the memory stores keep
+// behaving like the SQL ones.
+type stores struct {
+	cars    store.CarStoreInterface
+	engines store.EngineStoreInterface
+	tx      store.TxManager
+}
+
+// eachBackend runs test against fresh, empty memory and SQL stores. The SQL
+// run is skipped unless TEST_DATABASE_URL is set.
+func eachBackend(t *testing.T, test func(t *testing.T, s stores)) {
+	t.Run("memory", func(t *testing.T) {
+		db := memory.NewDB()
+		test(t, stores{cars: memory.NewCarStore(db), engines: memory.NewEngineStore(db), tx: memory.NewTxManager(db)})
+	})
+	t.Run("sql", func(t *testing.T) {
+		db := testdb.Open(t)
+		migrator, err := migrations.New(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("migrating: %v", err)
+		}
+		test(t, stores{cars: carStore.New(db), engines: engineStore.New(db), tx: uow.New(db)})
+	})
+}
+
+func createEngine(t *testing.T, s stores) models.Engine {
+	t.Helper()
+	engine, err := s.engines.CreateEngine(context.Background(), &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+	if err != nil {
+		t.Fatalf("CreateEngine: %v", err)
+	}
+	return engine
+}
+
+func carRequest(engineID uuid.UUID) *models.CarRequest {
+	return &models.CarRequest{
+		Name:     "Civic",
+		Year:     "2023",
+		Brand:    "Honda",
+		FuelType: "Petrol",
+		Engine:   models.Engine{EngineID: engineID},
+		Price:    models.Money{Amount: 2500000, Currency: models.BaseCurrency},
+	}
+}
+
+func createCar(t *testing.T, s stores, engineID uuid.UUID) models.Car {
+	t.Helper()
+	car, err := s.cars.CreateCar(context.Background(), carRequest(engineID))
+	if err != nil {
+		t.Fatalf("CreateCar: %v", err)
+	}
+	return car
+}
+
+func wantErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("got error %v, want %v", err, target)
+	}
+}
+
+func TestCreateAndGetCar(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		created := createCar(t, s, engine.EngineID)
+		if created.Version != 1 {
+			t.Errorf("a new car has version %d, want 1", created.Version)
+		}
+
+		car, err := s.cars.GetCarById(ctx, created.ID.String())
+		if err != nil {
+			t.Fatalf("GetCarById: %v", err)
+		}
+		if car.Name != "Civic" || car.Price != created.Price {
+			t.Errorf("read back %q for %v, want Civic for %v", car.Name, car.Price, created.Price)
+		}
+		if car.Engine.Displacement != engine.Displacement {
+			t.Errorf("the car's engine has displacement %d, want the engine joined in with %d", car.Engine.Displacement, engine.Displacement)
+		}
+	})
+}
+
+func TestCarErrors(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+
+		_, err := s.cars.GetCarById(ctx, uuid.NewString())
+		wantErr(t, err, models.ErrNotFound)
+		_, err = s.cars.GetCarById(ctx, "not-a-uuid")
+		wantErr(t, err, models.ErrInvalidID)
+		_, err = s.cars.CreateCar(ctx, carRequest(uuid.New()))
+		wantErr(t, err, models.ErrValidation)
+
+		req := carRequest(engine.EngineID)
+		req.Price.Currency = "JPY"
+		_, err = s.cars.CreateCar(ctx, req)
+		wantErr(t, err, models.ErrValidation)
+
+		_, err = s.cars.UpdateCar(ctx, uuid.NewString(), carRequest(engine.EngineID), 0)
+		wantErr(t, err, models.ErrNotFound)
+		_, err = s.engines.GetEngineById(ctx, uuid.NewString())
+		wantErr(t, err, models.ErrNotFound)
+	})
+}
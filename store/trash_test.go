@@ -0,0 +1,128 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestCarTrash(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		car := createCar(t, s, engine.EngineID)
+		id := car.ID.String()
+
+		_, err := s.cars.RestoreCar(ctx, id)
+		wantErr(t, err, models.ErrNotFound)
+
+		deleted, err := s.cars.DeleteCar(ctx, id, 0)
+		if err != nil {
+			t.Fatalf("DeleteCar: %v", err)
+		}
+		if deleted.DeletedAt == nil {
+			t.Fatal("the deleted car has no deleted_at")
+		}
+		_, err = s.cars.GetCarById(ctx, id)
+		wantErr(t, err, models.ErrNotFound)
+		_, err = s.cars.DeleteCar(ctx, id, 0)
+		wantErr(t, err, models.ErrNotFound)
+		page, err := s.cars.ListCars(ctx, models.CarFilter{SortBy: models.SortByCreatedAt, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Cars) != 0 {
+			t.Fatalf("the listing shows %d cars, want the deleted one hidden", len(page.Cars))
+		}
+		trash, err := s.cars.ListDeletedCars(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash) != 1 || trash[0].ID != car.ID {
+			t.Fatalf("the trash holds %v, want the deleted car", trash)
+		}
+
+		restored, err := s.cars.RestoreCar(ctx, id)
+		if err != nil {
+			t.Fatalf("RestoreCar: %v", err)
+		}
+		if restored.DeletedAt != nil || restored.Version != deleted.Version+1 {
+			t.Fatalf("restored car has deleted_at %v and version %d, want none and %d", restored.DeletedAt, restored.Version, deleted.Version+1)
+		}
+		if _, err := s.cars.GetCarById(ctx, id); err != nil {
+			t.Fatalf("the restored car cannot be read: %v", err)
+		}
+	})
+}
+
+func TestCarRestoreNeedsLiveEngine(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		car := createCar(t, s, engine.EngineID)
+		if _, err := s.engines.DeleteEngine(ctx, engine.EngineID.String(), 0, models.DeleteCascade); err != nil {
+			t.Fatalf("DeleteEngine: %v", err)
+		}
+
+		_, err := s.cars.RestoreCar(ctx, car.ID.String())
+		wantErr(t, err, models.ErrConflict)
+		if _, err := s.engines.RestoreEngine(ctx, engine.EngineID.String()); err != nil {
+			t.Fatalf("RestoreEngine: %v", err)
+		}
+		if _, err := s.cars.RestoreCar(ctx, car.ID.String()); err != nil {
+			t.Fatalf("RestoreCar once the engine is back: %v", err)
+		}
+	})
+}
+
+func TestPurge(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		spare := createEngine(t, s)
+		old := createCar(t, s, engine.EngineID)
+		recent := createCar(t, s, engine.EngineID)
+		live := createCar(t, s, engine.EngineID)
+		for _, car := range []string{old.ID.String(), recent.ID.String()} {
+			if _, err := s.cars.DeleteCar(ctx, car, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.engines.DeleteEngine(ctx, spare.EngineID.String(), 0, models.DeleteRestrict); err != nil {
+			t.Fatal(err)
+		}
+
+		purged, err := s.cars.PurgeCars(ctx, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeCars: %v", err)
+		}
+		if purged != 0 {
+			t.Fatalf("purged %d cars deleted within the retention period", purged)
+		}
+		purged, err = s.cars.PurgeCars(ctx, time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatalf("PurgeCars: %v", err)
+		}
+		if purged != 2 {
+			t.Fatalf("purged %d cars, want the 2 in the trash", purged)
+		}
+		_, err = s.cars.RestoreCar(ctx, old.ID.String())
+		wantErr(t, err, models.ErrNotFound)
+		if _, err := s.cars.GetCarById(ctx, live.ID.String()); err != nil {
+			t.Fatalf("the live car was purged: %v", err)
+		}
+
+		purged, err = s.engines.PurgeEngines(ctx, time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatalf("PurgeEngines: %v", err)
+		}
+		if purged != 1 {
+			t.Fatalf("purged %d engines, want the deleted one", purged)
+		}
+		if _, err := s.engines.GetEngineById(ctx, engine.EngineID.String()); err != nil {
+			t.Fatalf("the engine in use was purged: %v", err)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// Package uow runs several store calls as one unit of work. The transaction
+// of a unit travels in the context, and every SQL store method joins it when
+// one is present instead of opening its own, so the calls commit or roll
+// back together.
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+// Querier is what *sql.DB and *sql.Tx have in common, so reads can run
+// either on their own or inside the current unit's transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// Manager starts units of work on a database.
+type Manager struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Manager {
+	return &Manager{
+		db: db,
+	}
+}
+
+// Do runs fn in a transaction carried by the context it is given. The
+// transaction is committed if fn succeeds and rolled back if it returns an
+// error or panics. A Do nested in another unit joins the outer transaction,
+// which then decides the outcome for both.
+func (m *Manager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := InTx(ctx, m.db, func(tx *sql.Tx) (struct{}, error) {
+		return struct{}{}, fn(context.WithValue(ctx, txKey{}, tx))
+	})
+	return err
+}
+
+// InTx runs fn in the unit's transaction if the context carries one, or else
+// in a transaction of its own that it commits or rolls back once fn returns.
+func InTx[T any](ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) (T, error)) (T, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	var zero T
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(ctx, tx)
+			panic(p)
+		}
+	}()
+
+	result, err := fn(tx)
+	if err != nil {
+		rollback(ctx, tx)
+		return zero, err
+	}
+	if err := tx.Commit(); err != nil {
+		return zero, err
+	}
+	return result, nil
+}
+
+// Conn returns the unit's transaction if the context carries one, so reads
+// see what the unit has written so far, or else the database itself.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+func rollback(ctx context.Context, tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil {
+		slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestUnitOfWorkCommits(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		var car models.Car
+		err := s.tx.Do(context.Background(), func(ctx context.Context) error {
+			engine, err := s.engines.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+			if err != nil {
+				return err
+			}
+			car, err = s.cars.CreateCar(ctx, carRequest(engine.EngineID))
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		if _, err := s.cars.GetCarById(context.Background(), car.ID.String()); err != nil {
+			t.Fatalf("the committed car cannot be read: %v", err)
+		}
+	})
+}
+
+func TestUnitOfWorkRollsBack(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		var engineID, carID uuid.UUID
+		failure := errors.New("fail")
+		err := s.tx.Do(ctx, func(ctx context.Context) error {
+			engine, err := s.engines.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+			if err != nil {
+				return err
+			}
+			car, err := s.cars.CreateCar(ctx, carRequest(engine.EngineID))
+			if err != nil {
+				return err
+			}
+			engineID, carID = engine.EngineID, car.ID
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Do returned %v, want the unit's error", err)
+		}
+		_, err = s.engines.GetEngineById(ctx, engineID.String())
+		wantErr(t, err, models.ErrNotFound)
+		_, err = s.cars.GetCarById(ctx, carID.String())
+		wantErr(t, err, models.ErrNotFound)
+		_, err = s.cars.GetCarHistory(ctx, carID.String())
+		wantErr(t, err, models.ErrNotFound)
+	})
+}
+
+func TestUnitOfWorkRollsBackOnPanic(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		var engineID uuid.UUID
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("the panic was swallowed")
+				}
+			}()
+			s.tx.Do(ctx, func(ctx context.Context) error {
+				engine, err := s.engines.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+				if err != nil {
+					return err
+				}
+				engineID = engine.EngineID
+				panic("boom")
+			})
+		}()
+		_, err := s.engines.GetEngineById(ctx, engineID.String())
+		wantErr(t, err, models.ErrNotFound)
+	})
+}
+
+func TestNestedUnitsShareTheOuterOutcome(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		var engineID uuid.UUID
+		failure := errors.New("fail")
+		err := s.tx.Do(ctx, func(ctx context.Context) error {
+			err := s.tx.Do(ctx, func(ctx context.Context) error {
+				engine, err := s.engines.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+				engineID = engine.EngineID
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Do returned %v, want the outer unit's error", err)
+		}
+		_, err = s.engines.GetEngineById(ctx, engineID.String())
+		wantErr(t, err, models.ErrNotFound)
+	})
+}
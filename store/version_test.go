@@ -0,0 +1,73 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestCarVersions(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		car := createCar(t, s, engine.EngineID)
+		id := car.ID.String()
+
+		_, err := s.cars.UpdateCar(ctx, id, carRequest(engine.EngineID), car.Version+1)
+		wantErr(t, err, models.ErrPreconditionFailed)
+
+		updated, err := s.cars.UpdateCar(ctx, id, carRequest(engine.EngineID), car.Version)
+		if err != nil {
+			t.Fatalf("UpdateCar with the current version: %v", err)
+		}
+		if updated.Version != car.Version+1 {
+			t.Fatalf("an update left version %d, want %d", updated.Version, car.Version+1)
+		}
+
+		name := "Civic Type R"
+		_, err = s.cars.PatchCar(ctx, id, models.CarUpdate{Name: &name}, car.Version)
+		wantErr(t, err, models.ErrPreconditionFailed)
+		_, err = s.cars.DeleteCar(ctx, id, car.Version)
+		wantErr(t, err, models.ErrPreconditionFailed)
+
+		patched, err := s.cars.PatchCar(ctx, id, models.CarUpdate{Name: &name}, 0)
+		if err != nil {
+			t.Fatalf("PatchCar without a version: %v", err)
+		}
+		if patched.Version != updated.Version+1 {
+			t.Fatalf("a patch left version %d, want %d", patched.Version, updated.Version+1)
+		}
+		if _, err := s.cars.DeleteCar(ctx, id, patched.Version); err != nil {
+			t.Fatalf("DeleteCar with the current version: %v", err)
+		}
+	})
+}
+
+func TestEngineVersions(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		id := engine.EngineID.String()
+		req := &models.EngineRequest{Displacement: 2500, NoOfCylinders: 4, CarRange: 650}
+
+		_, err := s.engines.UpdateEngine(ctx, id, req, engine.Version+1)
+		wantErr(t, err, models.ErrPreconditionFailed)
+		updated, err := s.engines.UpdateEngine(ctx, id, req, engine.Version)
+		if err != nil {
+			t.Fatalf("UpdateEngine with the current version: %v", err)
+		}
+		if updated.Version != engine.Version+1 {
+			t.Fatalf("an update left version %d, want %d", updated.Version, engine.Version+1)
+		}
+
+		cylinders := int64(6)
+		_, err = s.engines.PatchEngine(ctx, id, models.EngineUpdate{NoOfCylinders: &cylinders}, engine.Version)
+		wantErr(t, err, models.ErrPreconditionFailed)
+		_, err = s.engines.DeleteEngine(ctx, id, engine.Version, models.DeleteRestrict)
+		wantErr(t, err, models.ErrPreconditionFailed)
+		if _, err := s.engines.DeleteEngine(ctx, id, updated.Version, models.DeleteRestrict); err != nil {
+			t.Fatalf("DeleteEngine with the current version: %v", err)
+		}
+	})
+}
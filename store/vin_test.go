@@ -0,0 +1,51 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestDuplicateVIN(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		engine := createEngine(t, s)
+		const vin = "1HGCM82633A004352"
+		withVIN := carRequest(engine.EngineID)
+		withVIN.VIN = vin
+
+		first, err := s.cars.CreateCar(ctx, withVIN)
+		if err != nil {
+			t.Fatalf("CreateCar: %v", err)
+		}
+		found, err := s.cars.GetCarByVIN(ctx, vin)
+		if err != nil || found.ID != first.ID {
+			t.Fatalf("GetCarByVIN found %v, %v, want the car", found.ID, err)
+		}
+		_, err = s.cars.CreateCar(ctx, withVIN)
+		wantErr(t, err, models.ErrConflict)
+
+		// cars without a VIN never clash
+		other := createCar(t, s, engine.EngineID)
+		createCar(t, s, engine.EngineID)
+		_, err = s.cars.UpdateCar(ctx, other.ID.String(), withVIN, 0)
+		wantErr(t, err, models.ErrConflict)
+		taken := vin
+		_, err = s.cars.PatchCar(ctx, other.ID.String(), models.CarUpdate{VIN: &taken}, 0)
+		wantErr(t, err, models.ErrConflict)
+
+		// a car in the trash frees its VIN, and cannot come back while
+		// another car has it
+		if _, err := s.cars.DeleteCar(ctx, first.ID.String(), 0); err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.cars.GetCarByVIN(ctx, vin)
+		wantErr(t, err, models.ErrNotFound)
+		if _, err := s.cars.PatchCar(ctx, other.ID.String(), models.CarUpdate{VIN: &taken}, 0); err != nil {
+			t.Fatalf("taking the VIN of a deleted car: %v", err)
+		}
+		_, err = s.cars.RestoreCar(ctx, first.ID.String())
+		wantErr(t, err, models.ErrConflict)
+	})
+}
@@ -0,0 +1,123 @@
+// Package vin decodes vehicle identification numbers offline, from a
+// bundled table of world manufacturer identifiers and the model year code.
+package vin
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+//go:embed wmi.csv
+var wmiCSV string
+
+type manufacturer struct {
+	name    string
+	brands  []string
+	country string
+}
+
+// wmis maps a world manufacturer identifier, the first three characters of
+// a VIN, to its manufacturer. A manufacturer selling several brands under
+// one WMI has a row for each, the main brand first.
+var wmis = loadWMIs()
+
+func loadWMIs() map[string]manufacturer {
+	records, err := csv.NewReader(strings.NewReader(wmiCSV)).ReadAll()
+	if err != nil {
+		panic("vin: invalid wmi.csv: " + err.Error())
+	}
+	table := make(map[string]manufacturer, len(records)-1)
+	for _, record := range records[1:] {
+		maker, ok := table[record[0]]
+		if !ok {
+			maker = manufacturer{name: record[1], country: record[3]}
+		}
+		maker.brands = append(maker.brands, record[2])
+		table[record[0]] = maker
+	}
+	return table
+}
+
+// yearCodes are the model year codes of the first 30 year cycle, starting
+// with 1980. The cycle repeated from 2010.
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// Decode tells what a VIN says about a vehicle: where and by whom it was
+// made and its model year. The VIN may be given in any case. A
+// manufacturer missing from the table leaves Manufacturer, Brand and Brands
+// empty.
+func Decode(number string) (models.DecodedVIN, error) {
+	number = models.NormalizeVIN(number)
+	if err := models.ValidateVIN(number); err != nil {
+		return models.DecodedVIN{}, err
+	}
+	decoded := models.DecodedVIN{
+		VIN:       number,
+		WMI:       number[:3],
+		VDS:       number[3:9],
+		VIS:       number[9:],
+		Region:    region(number[0]),
+		ModelYear: modelYear(number, time.Now()),
+	}
+	if maker, ok := wmis[decoded.WMI]; ok {
+		decoded.Manufacturer = maker.name
+		decoded.Brand = maker.brands[0]
+		decoded.Brands = slices.Clone(maker.brands)
+		decoded.Country = maker.country
+	}
+	return decoded, nil
+}
+
+// modelYear reads the model year code at the tenth position, or returns 0
+// if it is not one. Each code stands for two years 30 years apart. North
+// American VINs tell them apart by the seventh character, a letter since
+// 2010; for the others the latest year that is not after next year wins.
+func modelYear(number string, now time.Time) int {
+	index := strings.IndexByte(yearCodes, number[9])
+	if index < 0 {
+		return 0
+	}
+	year := 1980 + index
+	if NorthAmerican(number) {
+		if number[6] >= 'A' && number[6] <= 'Z' {
+			year += 30
+		}
+		return year
+	}
+	for year+30 <= now.Year()+1 {
+		year += 30
+	}
+	return year
+}
+
+// NorthAmerican tells whether a VIN was assigned in North America, where
+// the model year code is always used as such. Elsewhere manufacturers may
+// put something else at the tenth position, so a decoded year is only a
+// hint.
+func NorthAmerican(number string) bool {
+	return number != "" && number[0] >= '1' && number[0] <= '5'
+}
+
+// region returns the part of the world the first character of a VIN was
+// assigned to.
+func region(c byte) string {
+	switch {
+	case c >= 'A' && c <= 'H':
+		return "Africa"
+	case c >= 'J' && c <= 'R':
+		return "Asia"
+	case c >= 'S' && c <= 'Z':
+		return "Europe"
+	case c >= '1' && c <= '5':
+		return "North America"
+	case c == '6' || c == '7':
+		return "Oceania"
+	default:
+		return "South America"
+	}
+}
@@ -0,0 +1,92 @@
+package vin
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pranayyb/DriveThrough/models"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		vin          string
+		region       string
+		country      string
+		manufacturer string
+		brands       []string
+		year         int
+	}{
+		{"1HGCM82633A004352", "North America", "United States", "Honda of America", []string{"Honda"}, 2003},
+		{"2hgfe2f53ph500123", "North America", "Canada", "Honda of Canada", []string{"Honda"}, 2023},
+		{"5YJ3E1EAXJF000001", "North America", "United States", "Tesla", []string{"Tesla"}, 2018},
+		{"JN1AZ4EH7DM430111", "Asia", "Japan", "Nissan Motor Company", []string{"Nissan", "Infiniti"}, 2013},
+		{"KMHD84LFXJU123456", "Asia", "South Korea", "Hyundai Motor Company", []string{"Hyundai", "Genesis"}, 2018},
+		{"WBA5R1C04PFN12345", "Europe", "Germany", "BMW", []string{"BMW"}, 2023},
+		{"11111111111111111", "North America", "", "", nil, 2001},
+	}
+	for _, test := range tests {
+		t.Run(test.vin, func(t *testing.T) {
+			decoded, err := Decode(test.vin)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if decoded.VIN != models.NormalizeVIN(test.vin) || decoded.WMI != decoded.VIN[:3] || decoded.VDS+decoded.VIS != decoded.VIN[3:] {
+				t.Errorf("split %s into %s, %s and %s", decoded.VIN, decoded.WMI, decoded.VDS, decoded.VIS)
+			}
+			if decoded.Region != test.region || decoded.Country != test.country || decoded.Manufacturer != test.manufacturer {
+				t.Errorf("decoded %s, %s, %s, want %s, %s, %s", decoded.Region, decoded.Country, decoded.Manufacturer, test.region, test.country, test.manufacturer)
+			}
+			if !slices.Equal(decoded.Brands, test.brands) || (len(test.brands) > 0 && decoded.Brand != test.brands[0]) {
+				t.Errorf("decoded brand %q of %q, want %q", decoded.Brand, decoded.Brands, test.brands)
+			}
+			if decoded.ModelYear != test.year {
+				t.Errorf("decoded model year %d, want %d", decoded.ModelYear, test.year)
+			}
+		})
+	}
+}
+
+func TestDecodeDoesNotShareBrands(t *testing.T) {
+	decoded, _ := Decode("JN1AZ4EH7DM430111")
+	decoded.Brands[0] = "Datsun"
+	if again, _ := Decode("JN1AZ4EH7DM430111"); again.Brand != "Nissan" {
+		t.Errorf("changing a decoded VIN changed the table: got %s", again.Brand)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, number := range []string{"", "1HGCM82633A00435", "1HGCM82634A004352", "1HGCM82633A0O4352"} {
+		if _, err := Decode(number); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Decode(%q) = %v, want a validation error", number, err)
+		}
+	}
+}
+
+func TestModelYear(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		number string
+		want   int
+	}{
+		{"north american, digit at seven", "1HGCM82633A004352", 2003},
+		{"north american, letter at seven", "1HGCM8D633A004352", 2033},
+		{"north american 1980", "5YJ3E1EAXAF000001", 2010},
+		{"north american 1980s", "5YJ3E11AXAF000001", 1980},
+		{"elsewhere, latest past year", "WBA5R1C04PFN12345", 2023},
+		{"elsewhere, next year", "WBA5R1C04TFN12345", 2026},
+		{"elsewhere, next year's code", "WBA5R1C04VFN12345", 2027},
+		{"elsewhere, the year after next is a cycle ago", "WBA5R1C04WFN12345", 1998},
+		{"no year code", "WBA5R1C04ZFN12345", 0},
+		{"zero is no year code", "WBA5R1C040FN12345", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := modelYear(test.number, now); got != test.want {
+				t.Errorf("modelYear(%s) = %d, want %d", test.number, got, test.want)
+			}
+		})
+	}
+}